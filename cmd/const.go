@@ -4,6 +4,8 @@ const (
 	createDescription = `
 Create a universal binary (also known as a fat binary) from input thin binaries.
 e.g. lipo path/to/binary.x86_64 path/to/binary.arm64e -create -output path/to/fat-binary
+Universal binaries sharing architectures can be merged with -merge_policy.
+e.g. lipo path/to/fat-binary1 path/to/fat-binary2 -create -merge_policy first -output path/to/fat-binary
 `
 	extractDescription = `
 Extract the specified architecture from a universal binary and create a new universal binary.
@@ -19,6 +21,7 @@ e.g. lipo path/to/fat-binary -remove x86_64 -remove x86_64h -output path/to/new-
 `
 	replaceDescription = `
 Replace the specified architecture in a universal binary with the specified input binary.
+If the input binary is a universal binary, the specified architecture is selected from it.
 e.g. lipo path/to/fat-binary -replace x86_64 path/to/binary.x86_64 -output path/to/new-fat-binary
 `
 	thinDescription = `
@@ -42,5 +45,65 @@ e.g. lipo path/to/fat-binary path/to/binary.x86_64 -info
 	detailedInfoDescription = `
 Display detailed information about universal binaries.
 e.g. lipo path/to/fat-binary path/to/binary.x86_64 -detailed_info
+`
+
+	splitDescription = `
+Write every architecture of a universal binary to the directory with a manifest file.
+The architectures include hidden architectures and archives.
+e.g. lipo path/to/fat-binary -split path/to/dir
+`
+
+	joinDescription = `
+Recreate a universal binary from the manifest file written by -split.
+e.g. lipo -join path/to/dir/manifest.json -output path/to/fat-binary
+`
+
+	scanDescription = `
+Walk a directory and display architectures of every Mach-O file, universal binary and static archive.
+The exit status is 1 when any of them cannot be read such as a corrupted file or an archive mixing architectures.
+If -verify_arch is specified, the exit status is 1 when any of them lacks the specified architectures.
+e.g. lipo -scan path/to/App.app -verify_arch x86_64 arm64
+`
+
+	keepArchDescription = `
+Walk a directory and keep only the specified architectures in every universal binary in place.
+Permissions are preserved. Symbolic links, thin files and other files are not changed.
+If -output is specified, the input is a .zip or .ipa file and the thinned zip file is written to the output.
+e.g. lipo path/to/App.app -keep_arch arm64
+e.g. lipo App.ipa -keep_arch arm64 -output App-arm64.ipa
+`
+
+	createXCFrameworkDescription = `
+Create an xcframework from libraries or frameworks built for platforms such as ios, ios simulator and macos.
+Slices of the same platform are merged into a universal binary and Info.plist is generated.
+e.g. lipo path/to/ios/libfoo.a path/to/ios-simulator/libfoo.a -create_xcframework -output path/to/Foo.xcframework
+`
+
+	inspectXCFrameworkDescription = `
+Display libraries of an xcframework and validate Info.plist against the actual architectures and platforms.
+The exit status is 1 when any of them does not match.
+e.g. lipo -inspect_xcframework path/to/Foo.xcframework
+`
+
+	artifactBundleDescription = `
+Create a Swift Package Manager artifact bundle from executables and generate info.json.
+Every architecture becomes a variant of the target triple such as arm64-apple-macosx.
+If -universal is specified, macOS architectures are merged into a universal binary as a variant.
+The name of the artifact is the base name of the first input unless -artifact_name is specified.
+e.g. lipo path/to/tool.x86_64 path/to/tool.arm64 -create_artifact_bundle -artifact_version 1.0.0 -universal -output path/to/tool.artifactbundle
+`
+
+	splitArchiveDescription = `
+Write a thin static library for every architecture of members of a static library to the directory.
+Members of different architectures and universal members are allowed and symbol tables are regenerated.
+If -output is specified, the thin static libraries are combined into a universal static library.
+e.g. lipo path/to/libfoo.a -split_archive path/to/dir -output path/to/libfoo-fat.a
+`
+
+	createStaticDescription = `
+Merge members of the same architecture of static libraries and objects into a static library like libtool -static and create a universal static library.
+Symbol tables are regenerated. Members which have the same name and contents are added once and
+members which have the same name but different contents are renamed to <name>-<n>.o in the order of the inputs.
+e.g. lipo path/to/libfoo.a path/to/libbar-fat.a -create_static -output path/to/libfoobar.a
 `
 )
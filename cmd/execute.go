@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/konoui/lipo/pkg/lipo"
@@ -33,12 +36,24 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 	verifyArchGroup := fset.NewGroup("verify_arch").AddDescription(verifyArchDescription)
 	infoGroup := fset.NewGroup("info").AddDescription(infoDescription)
 	detailedInfoGroup := fset.NewGroup("detailed_info").AddDescription(detailedInfoDescription)
+	splitGroup := fset.NewGroup("split").AddDescription(splitDescription)
+	joinGroup := fset.NewGroup("join").AddDescription(joinDescription)
+	scanGroup := fset.NewGroup("scan").AddDescription(scanDescription)
+	keepArchGroup := fset.NewGroup("keep_arch").AddDescription(keepArchDescription)
+	createXCFrameworkGroup := fset.NewGroup("create_xcframework").AddDescription(createXCFrameworkDescription)
+	inspectXCFrameworkGroup := fset.NewGroup("inspect_xcframework").AddDescription(inspectXCFrameworkDescription)
+	artifactBundleGroup := fset.NewGroup("create_artifact_bundle").AddDescription(artifactBundleDescription)
+	splitArchiveGroup := fset.NewGroup("split_archive").AddDescription(splitArchiveDescription)
+	createStaticGroup := fset.NewGroup("create_static").AddDescription(createStaticDescription)
 	groups := []*sflag.Group{
 		helpGroup, versionGroup,
 		createGroup, thinGroup, extractGroup,
 		extractFamilyGroup, removeGroup, replaceGroup,
 		archsGroup, verifyArchGroup, infoGroup,
-		detailedInfoGroup,
+		detailedInfoGroup, splitGroup, joinGroup,
+		scanGroup, keepArchGroup,
+		createXCFrameworkGroup, inspectXCFrameworkGroup,
+		artifactBundleGroup, splitArchiveGroup, createStaticGroup,
 	}
 	fset.Usage = sflag.UsageFunc(groups...)
 	// original lipo does not have help/version command.
@@ -47,6 +62,7 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 	out := fset.String("output", "-output <output_file>", sflag.WithShortName("o"))
 	segAligns := fset.FixedStringFlags("segalign", "-segalign <arch_type> <alignment>", sflag.WithShortName("s"))
 	arch := fset.FixedStringFlags("arch", "-arch <arch_type> <input_file>", sflag.WithShortName("a"))
+	archBlank := fset.StringFlags("arch_blank", "-arch_blank <arch_type> [-arch_blank <arch_type> ...]")
 	create := fset.Bool("create", "-create", sflag.WithShortName("c"))
 	thin := fset.String("thin", "-thin <arch_type>", sflag.WithShortName("t"))
 	extract := fset.StringFlags("extract", "-extract <arch_type> [-extract <arch_type> ...]", sflag.WithShortName("e"))
@@ -57,47 +73,92 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 	verifyArch := fset.Strings("verify_arch", "-verify_arch <arch_type> ...")
 	info := fset.Bool("info", "-info", sflag.WithShortName("i"))
 	detailedInfo := fset.Bool("detailed_info", "-detailed_info", sflag.WithShortName("d"))
+	split := fset.String("split", "-split <directory>")
+	join := fset.String("join", "-join <manifest_file>")
+	scan := fset.String("scan", "-scan <directory>")
+	keepArch := fset.Strings("keep_arch", "-keep_arch <arch_type> ...")
+	createXCFramework := fset.Bool("create_xcframework", "-create_xcframework")
+	inspectXCFramework := fset.String("inspect_xcframework", "-inspect_xcframework <xcframework>")
+	artifactBundle := fset.Bool("create_artifact_bundle", "-create_artifact_bundle")
+	artifactName := fset.String("artifact_name", "-artifact_name <name>")
+	artifactVersion := fset.String("artifact_version", "-artifact_version <version>")
+	universal := fset.Bool("universal", "-universal")
+	splitArchive := fset.String("split_archive", "-split_archive <directory>")
+	createStatic := fset.Bool("create_static", "-create_static")
 	hideArm64 := fset.Bool("hideARM64", "-hideARM64")
+	hideArch := fset.StringFlags("hide_arch", "-hide_arch <arch_type> [-hide_arch <arch_type> ...]")
 	fat64 := fset.Bool("fat64", "-fat64")
+	order := fset.Strings("order", "-order <arch_type> ...")
+	verifyOutput := fset.Bool("verify_output", "-verify_output")
+	dryRun := fset.Bool("dry_run", "-dry_run")
+	progress := fset.Bool("progress", "-progress")
+	mergePolicy := fset.String("merge_policy", "-merge_policy <error|first|last|identical-only>")
 
 	helpGroup.AddRequired(help)
 	versionGroup.AddRequired(version)
 	createGroup.
 		AddRequired(create).
 		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun).
 		AddOptional(segAligns).
 		AddOptional(arch).
+		AddOptional(archBlank).
+		AddOptional(mergePolicy).
 		AddOptional(hideArm64).
-		AddOptional(fat64)
+		AddOptional(hideArch).
+		AddOptional(fat64).
+		AddOptional(order)
 	thinGroup.
 		// apple lipo does not raise error if -thin with -segalign but this this lipo will raise an error
 		AddRequired(thin).
-		AddRequired(out)
+		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress)
 	extractGroup.
 		AddRequired(extract).
 		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun).
 		AddOptional(segAligns).
-		AddOptional(fat64)
+		AddOptional(fat64).
+		AddOptional(order)
 	extractFamilyGroup.
 		AddRequired(extractFamily).
 		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun).
 		// if extract is specified, apple lipo regard values as family
 		AddOptional(extract).
 		AddOptional(segAligns).
-		AddOptional(fat64)
+		AddOptional(fat64).
+		AddOptional(order)
 	removeGroup.
 		AddRequired(remove).
 		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun).
 		AddOptional(segAligns).
 		AddOptional(hideArm64).
-		AddOptional(fat64)
+		AddOptional(hideArch).
+		AddOptional(fat64).
+		AddOptional(order)
 	replaceGroup.
 		AddRequired(replace).
 		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun).
 		AddOptional(segAligns).
 		AddOptional(arch).
 		AddOptional(hideArm64).
-		AddOptional(fat64)
+		AddOptional(hideArch).
+		AddOptional(fat64).
+		AddOptional(order)
 	archsGroup.
 		AddRequired(archs)
 	verifyArchGroup.
@@ -106,6 +167,49 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 		AddRequired(info)
 	detailedInfoGroup.
 		AddRequired(detailedInfo)
+	splitGroup.
+		AddRequired(split)
+	scanGroup.
+		AddRequired(scan).
+		AddOptional(verifyArch)
+	keepArchGroup.
+		AddRequired(keepArch).
+		AddOptional(out).
+		AddOptional(verifyOutput)
+	createXCFrameworkGroup.
+		AddRequired(createXCFramework).
+		AddRequired(out).
+		AddOptional(progress)
+	inspectXCFrameworkGroup.
+		AddRequired(inspectXCFramework)
+	artifactBundleGroup.
+		AddRequired(artifactBundle).
+		AddRequired(artifactVersion).
+		AddRequired(out).
+		AddOptional(artifactName).
+		AddOptional(universal).
+		AddOptional(progress)
+	splitArchiveGroup.
+		AddRequired(splitArchive).
+		AddOptional(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(hideArm64).
+		AddOptional(fat64).
+		AddOptional(order)
+	createStaticGroup.
+		AddRequired(createStatic).
+		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(fat64).
+		AddOptional(order)
+	joinGroup.
+		AddRequired(join).
+		AddRequired(out).
+		AddOptional(verifyOutput).
+		AddOptional(progress).
+		AddOptional(dryRun)
 
 	if err := fset.Parse(args); err != nil {
 		fmt.Fprintf(stderr, "ParseError: %s\n", err.Error())
@@ -131,7 +235,11 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 		lipo.WithOutput(out.Get()),
 		lipo.WithInputs(in...),
 		lipo.WithArch(conv(arch.Get(), newArch)...),
+		lipo.WithArchBlank(archBlank.Get()...),
 		lipo.WithSegAlign(conv(segAligns.Get(), newSegAlign)...),
+		lipo.WithHidden(hideArch.Get()...),
+		lipo.WithOrder(order.Get()...),
+		lipo.WithStdout(stdout),
 	}
 	if hideArm64.Get() {
 		opts = append(opts, lipo.WithHideArm64())
@@ -139,40 +247,148 @@ func Execute(stdout, stderr io.Writer, args []string) (exitCode int) {
 	if fat64.Get() {
 		opts = append(opts, lipo.WithFat64())
 	}
+	if verifyOutput.Get() {
+		opts = append(opts, lipo.WithVerifyOutput())
+	}
+	if dryRun.Get() {
+		opts = append(opts, lipo.WithDryRun(stdout))
+	}
+	if progress.Get() {
+		pp := &progressPrinter{w: stderr}
+		defer pp.done()
+		opts = append(opts, lipo.WithProgress(pp.print))
+	}
+	if v := mergePolicy.Get(); v != "" {
+		policy, err := lipo.ParseMergePolicy(v)
+		if err != nil {
+			return fatal(stderr, err.Error())
+		}
+		opts = append(opts, lipo.WithMergePolicy(policy))
+	}
+	// remove a temporary output on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	l := lipo.New(opts...)
+	defer l.Close()
 	switch group.Name {
 	case "create":
-		if err := l.Create(); err != nil {
+		if err := l.CreateContext(ctx); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
 	case "thin":
-		if err := l.Thin(thin.Get()); err != nil {
+		if err := l.ThinContext(ctx, thin.Get()); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
 	case "remove":
-		if err := l.Remove(remove.Get()...); err != nil {
+		if err := l.RemoveContext(ctx, remove.Get()...); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
 	case "extract":
-		if err := l.Extract(extract.Get()...); err != nil {
+		if err := l.ExtractContext(ctx, extract.Get()...); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
 	case "extract_family":
 		extractFamily := extractFamily.Get()
 		extractFamily = append(extractFamily, extract.Get()...)
-		if err := l.ExtractFamily(extractFamily...); err != nil {
+		if err := l.ExtractFamilyContext(ctx, extractFamily...); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
 	case "replace":
-		if err := l.Replace(conv(replace.Get(), newArch)); err != nil {
+		if err := l.ReplaceContext(ctx, conv(replace.Get(), newArch)); err != nil {
+			return fatal(stderr, err.Error())
+		}
+		return
+	case "split":
+		if _, err := l.SplitContext(ctx, split.Get()); err != nil {
+			return fatal(stderr, err.Error())
+		}
+		return
+	case "join":
+		if err := l.JoinContext(ctx, join.Get()); err != nil {
 			return fatal(stderr, err.Error())
 		}
 		return
+	case "scan":
+		results, err := l.Scan(scan.Get(), verifyArch.Get()...)
+		if err != nil {
+			return fatal(stderr, err.Error())
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(stderr, "error: %s\n", r.Err.Error())
+				exitCode = 1
+				continue
+			}
+			fmt.Fprintln(stdout, r.Info())
+			if len(r.Missing) > 0 {
+				fmt.Fprintf(stderr, "error: %s does not contain the required architectures: %s\n", r.Path, strings.Join(r.Missing, " "))
+				exitCode = 1
+			}
+		}
+		return
+	case "keep_arch":
+		if len(in) != 1 {
+			return fatal(stderr, "only one input directory or zip file can be specified")
+		}
+		keep := l.KeepArchesContext
+		if out.Get() != "" {
+			keep = l.KeepArchesZipContext
+		}
+		results, err := keep(ctx, in[0], keepArch.Get()...)
+		if err != nil {
+			return fatal(stderr, err.Error())
+		}
+		total := int64(0)
+		for _, r := range results {
+			fmt.Fprintf(stdout, "%s: removed %s, %d -> %d bytes (saved %d bytes)\n", r.Path, strings.Join(r.Removed, " "), r.Before, r.After, r.Saved())
+			total += r.Saved()
+		}
+		fmt.Fprintf(stdout, "saved %d bytes in %d files\n", total, len(results))
+		return
+	case "create_xcframework":
+		if _, err := l.CreateXCFrameworkContext(ctx); err != nil {
+			return fatal(stderr, err.Error())
+		}
+		return
+	case "create_artifact_bundle":
+		if _, err := l.CreateArtifactBundleContext(ctx, artifactName.Get(), artifactVersion.Get(), universal.Get()); err != nil {
+			return fatal(stderr, err.Error())
+		}
+		return
+	case "split_archive":
+		results, err := l.SplitArchiveContext(ctx, splitArchive.Get())
+		if err != nil {
+			return fatal(stderr, err.Error())
+		}
+		for _, r := range results {
+			fmt.Fprintf(stdout, "%s: %s (%d members)\n", r.Path, r.Arch, len(r.Members))
+		}
+		return
+	case "create_static":
+		if err := l.CreateStaticContext(ctx); err != nil {
+			return fatal(stderr, err.Error())
+		}
+		return
+	case "inspect_xcframework":
+		libs, err := l.InspectXCFramework(inspectXCFramework.Get())
+		if err != nil {
+			return fatal(stderr, err.Error())
+		}
+		for _, lib := range libs {
+			if lib.Err != nil {
+				fmt.Fprintf(stderr, "error: %s\n", lib.Err.Error())
+				exitCode = 1
+				continue
+			}
+			fmt.Fprintln(stdout, lib.Info())
+		}
+		return
 	case "archs":
 		arches, err := l.Archs()
 		if err != nil {
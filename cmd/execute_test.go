@@ -154,6 +154,36 @@ func TestExecute(t *testing.T) {
 			name:         "TODO usage if no inputs",
 			wantExitCode: 1,
 		},
+		{
+			name:         "create with unsupported merge_policy",
+			args:         []string{"-create", "-output", phOutput, phInputThins, "-merge_policy", "unknown"},
+			wantErrMsg:   "unsupported merge policy: unknown",
+			wantExitCode: 1,
+		},
+		{
+			name:         "scan with unsupported verify_arch",
+			args:         []string{"-scan", ".", "-verify_arch", "unknown"},
+			wantErrMsg:   "unsupported architecture: unknown",
+			wantExitCode: 1,
+		},
+		{
+			name:         "scan with an unreadable file",
+			args:         []string{"-scan", "../pkg/ar/testdata"},
+			wantErrMsg:   "error: archive member ../pkg/ar/testdata/arm64-amd64-func12.a(arm64-func1.o)",
+			wantExitCode: 1,
+		},
+		{
+			name:         "split_archive with fat options",
+			args:         []string{"-split_archive", "out", "../pkg/ar/testdata/arm64-func1.o", "-fat64", "-order", "arm64", "-hideARM64"},
+			wantErrMsg:   "invalid magic header",
+			wantExitCode: 1,
+		},
+		{
+			name:         "keep_arch of zip without output",
+			args:         []string{"App.ipa", "-keep_arch", "arm64"},
+			wantErrMsg:   "the output must be specified for zip file: App.ipa",
+			wantExitCode: 1,
+		},
 		{
 			name:         "create but no input",
 			args:         []string{"-create", "-output", "out", "in", "in"},
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+// progressPrinter renders the progress on a single line.
+// The line is updated only when percentages change.
+type progressPrinter struct {
+	w    io.Writer
+	last string
+}
+
+func (p *progressPrinter) print(v lmacho.Progress) {
+	line := fmt.Sprintf("\rwriting %s %3d%%, total %3d%%", v.Arch, percent(v.Written, v.Size), percent(v.TotalWritten, v.Total))
+	if line == p.last {
+		return
+	}
+	p.last = line
+	fmt.Fprint(p.w, line)
+}
+
+// done terminates the line if the progress is printed
+func (p *progressPrinter) done() {
+	if p.last != "" {
+		fmt.Fprintln(p.w)
+	}
+}
+
+func percent(n, total uint64) uint64 {
+	if total == 0 {
+		return 100
+	}
+	return n * 100 / total
+}
@@ -1,5 +1,7 @@
 module github.com/konoui/lipo
 
-go 1.25
+go 1.25.0
 
 require github.com/konoui/go-qsort v0.1.0
+
+require golang.org/x/sys v0.47.0
@@ -16,11 +16,18 @@ const (
 	headerSize       = 60
 	PrefixSymdef     = "__.SYMDEF"
 	bsdVariantMarker = "#1/"
+	// GNUSymbolTable is the name of the symbol table of GNU/SysV archives
+	GNUSymbolTable = "/"
+	// GNUSymbolTable64 is the name of the 64-bit symbol table of GNU/SysV archives
+	GNUSymbolTable64 = "/SYM64/"
 )
 
 var (
 	MagicHeader      = []byte("!<arch>\n")
+	thinMagicHeader  = []byte("!<thin>\n")
 	ErrInvalidFormat = errors.New("not ar file format")
+	// ErrThinArchive is returned for GNU thin archives which refer to members outside of the archive
+	ErrThinArchive = errors.New("GNU thin archives are not supported since members are not stored in the archive")
 )
 
 type File struct {
@@ -41,6 +48,8 @@ type Header struct {
 
 type Iter struct {
 	sr *io.SectionReader
+	// names is the GNU `//` long-name table
+	names []byte
 }
 
 func NewArchive(ra io.ReaderAt) ([]*File, error) {
@@ -69,6 +78,10 @@ func NewIter(r io.ReaderAt) (*Iter, error) {
 		return nil, err
 	}
 
+	if bytes.Equal(thinMagicHeader, buf) {
+		return nil, ErrThinArchive
+	}
+
 	if !bytes.Equal(MagicHeader, buf) {
 		return nil, fmt.Errorf("invalid magic header want: %s, got: %s: %w",
 			string(MagicHeader), string(buf), ErrInvalidFormat)
@@ -77,31 +90,46 @@ func NewIter(r io.ReaderAt) (*Iter, error) {
 	return &Iter{sr: sr}, nil
 }
 
+// Next yields members of the archive. The GNU `//` long-name table is consumed to resolve names and is not yielded.
 func (r *Iter) Next() iter.Seq2[*File, error] {
 	return func(yield func(*File, error) bool) {
 		cur := int64(len(MagicHeader))
 		for {
-			f, err := load(r.sr, cur)
+			f, err := r.load(cur)
 			if errors.Is(err, io.EOF) {
 				return
 			}
 
+			if err == nil && f.Name == gnuNameTable {
+				r.names = make([]byte, f.SectionReader.Size())
+				_, err = io.ReadFull(f, r.names)
+				if err == nil {
+					cur += f.Header.Size + headerSize + f.Header.Size%2
+					continue
+				}
+			}
+
 			if !yield(f, err) {
 				return
 			}
 			if err != nil {
 				return
 			}
-			cur += f.Header.Size + headerSize
+			// members are aligned to 2 bytes. the padding is not included in the size.
+			cur += f.Header.Size + headerSize + f.Header.Size%2
 		}
 	}
 }
 
-func load(sr *io.SectionReader, off int64) (*File, error) {
+func (r *Iter) load(off int64) (*File, error) {
+	sr := r.sr
 	hdr, err := readHeader(sr, off)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.resolveGNUName(hdr); err != nil {
+		return nil, err
+	}
 
 	filesr := io.NewSectionReader(sr,
 		off+headerSize+hdr.nameSize,
@@ -149,28 +177,56 @@ func readHeader(sr *io.SectionReader, off int64) (*Header, error) {
 	return hdr, nil
 }
 
+// resolveGNUName resolves `/N` references to the long-name table and trims the trailing `/` of GNU names
+func (r *Iter) resolveGNUName(hdr *Header) error {
+	name := hdr.Name
+	if hdr.nameSize > 0 || name == GNUSymbolTable || name == GNUSymbolTable64 || name == gnuNameTable {
+		return nil
+	}
+
+	if strings.HasPrefix(name, "/") {
+		off, err := parseDecimal(name[1:])
+		if err != nil {
+			return fmt.Errorf("invalid long name reference %s: %w", name, err)
+		}
+		if r.names == nil {
+			return fmt.Errorf("long name reference %s without the name table", name)
+		}
+		if off < 0 || off >= int64(len(r.names)) {
+			return fmt.Errorf("long name reference %s is out of the name table", name)
+		}
+		long, _, ok := bytes.Cut(r.names[off:], []byte("\n"))
+		if !ok {
+			return fmt.Errorf("long name reference %s is not terminated", name)
+		}
+		name = string(long)
+	}
+	hdr.Name = strings.TrimSuffix(name, "/")
+	return nil
+}
+
 func parseHeader(buf [headerSize]byte) (*Header, error) {
 	name := TrimTailSpace(buf[0:16])
 
-	parsedMTime, err := parseDecimal(TrimTailSpace(buf[16:28]))
+	parsedMTime, err := parseDecimal(blankAsZero(TrimTailSpace(buf[16:28])))
 	if err != nil {
 		return nil, fmt.Errorf("parse modtime: %w", err)
 	}
 	modTime := time.Unix(parsedMTime, 0)
 
-	parsedUID, err := parseDecimal(TrimTailSpace(buf[28:34]))
+	parsedUID, err := parseDecimal(blankAsZero(TrimTailSpace(buf[28:34])))
 	if err != nil {
 		return nil, fmt.Errorf("parse uid: %w", err)
 	}
 
-	parsedGID, err := parseDecimal(TrimTailSpace(buf[34:40]))
+	parsedGID, err := parseDecimal(blankAsZero(TrimTailSpace(buf[34:40])))
 	if err != nil {
 		return nil, fmt.Errorf("parse gid: %w", err)
 	}
 
 	uid, gid := int(parsedUID), int(parsedGID)
 
-	parsedPerm, err := parseOctal(TrimTailSpace(buf[40:48]))
+	parsedPerm, err := parseOctal(blankAsZero(TrimTailSpace(buf[40:48])))
 	if err != nil {
 		return nil, fmt.Errorf("parse mode: %w", err)
 	}
@@ -197,6 +253,14 @@ func parseHeader(buf [headerSize]byte) (*Header, error) {
 	}, nil
 }
 
+// blankAsZero returns "0" for blank fields of special members like the GNU `//` table
+func blankAsZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
 func parseDecimal(s string) (int64, error) {
 	return strconv.ParseInt(s, 10, 64)
 }
@@ -5,11 +5,13 @@ import (
 	"crypto/sha256"
 	"debug/macho"
 	"encoding/hex"
+	"errors"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -162,3 +164,104 @@ func Test_TrimTailSpace(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_GNU(t *testing.T) {
+	files := openArchive(t, filepath.Join("testdata", "gnu-func12.a"))
+
+	// the long name and odd sized member created by `llvm-ar --format=gnu`
+	want := map[string]string{
+		ar.GNUSymbolTable:         "",
+		"arm64-func1.o":           "arm64-func1.o",
+		"arm64-func2-long-name.o": "arm64-func2.o",
+		"func1.c":                 "func1.c",
+	}
+	got := []string{}
+	for _, f := range files {
+		got = append(got, f.Name)
+		src, ok := want[f.Name]
+		if !ok {
+			t.Fatalf("unexpected member %s", f.Name)
+		}
+		if src == "" {
+			continue
+		}
+		gotHash := sha256String(t, f)
+		wantHash := sha256StringFromFile(t, filepath.Join("testdata", src))
+		if gotHash != wantHash {
+			t.Errorf("%s: want: %s got: %s", f.Name, wantHash, gotHash)
+		}
+	}
+	wantNames := []string{ar.GNUSymbolTable, "arm64-func1.o", "arm64-func2-long-name.o", "func1.c"}
+	if !reflect.DeepEqual(wantNames, got) {
+		t.Errorf("want: %v, got: %v", wantNames, got)
+	}
+
+	st, err := ar.ParseSymbolTable(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSyms := []*ar.Symbol{{Name: "_func1", Offset: 180}, {Name: "_func2", Offset: 960}}
+	if !st.IsGNU() || !reflect.DeepEqual(wantSyms, st.Symbols) {
+		t.Errorf("want %v got %v", wantSyms, st.Symbols)
+	}
+	if err := ar.ValidateSymbolTable(files); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNew_GNURoundTrip(t *testing.T) {
+	members := map[string][]byte{
+		"short.o":                         []byte("odd"),
+		"a-very-long-member-name.o":       []byte("even"),
+		"another-very-long-member-name.o": []byte("x"),
+	}
+	names := []string{"short.o", "a-very-long-member-name.o", "another-very-long-member-name.o"}
+
+	buf := &bytes.Buffer{}
+	w := ar.NewWriter(buf, ar.WithFormat(ar.FormatGNU))
+	for _, name := range names {
+		data := members[name]
+		if err := w.Add(&ar.Header{Name: name, Size: int64(len(data)), Mode: 0100644}, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ar.NewArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(names) {
+		t.Fatalf("want %d members got %d", len(names), len(files))
+	}
+	for i, f := range files {
+		if f.Name != names[i] {
+			t.Errorf("want %s got %s", names[i], f.Name)
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(members[f.Name], data) {
+			t.Errorf("%s: want %q got %q", f.Name, members[f.Name], data)
+		}
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	t.Run("thin archive", func(t *testing.T) {
+		_, err := ar.NewArchive(bytes.NewReader([]byte("!<thin>\n")))
+		if !errors.Is(err, ar.ErrThinArchive) {
+			t.Errorf("want ErrThinArchive got %v", err)
+		}
+	})
+
+	t.Run("long name without the table", func(t *testing.T) {
+		data := append(slices.Clone(ar.MagicHeader), "/0              0           0     0     644     0         `\n"...)
+		if _, err := ar.NewArchive(bytes.NewReader(data)); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
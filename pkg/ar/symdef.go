@@ -0,0 +1,402 @@
+package ar
+
+import (
+	"bytes"
+	"cmp"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	symdefSorted   = PrefixSymdef + " SORTED"
+	symdef64       = PrefixSymdef + "_64"
+	symdef64Sorted = PrefixSymdef + "_64 SORTED"
+)
+
+// https://github.com/apple-oss-distributions/cctools/blob/cctools-1021.4/include/mach-o/ranlib.h
+const (
+	// sizeof(struct ranlib)
+	ranlibSize = 8
+	// sizeof(struct ranlib_64)
+	ranlib64Size = 16
+)
+
+// Symbol is an entry of the symbol table. Offset is the offset of the member header in the archive.
+type Symbol struct {
+	Name   string
+	Offset int64
+}
+
+// SymbolTable presents `__.SYMDEF`, `__.SYMDEF SORTED`, `__.SYMDEF_64` or `__.SYMDEF_64 SORTED`,
+// or `/` and `/SYM64/` of GNU/SysV archives
+type SymbolTable struct {
+	Name    string
+	Symbols []*Symbol
+}
+
+func (st *SymbolTable) Is64() bool {
+	return strings.HasPrefix(st.Name, symdef64) || st.Name == GNUSymbolTable64
+}
+
+// IsGNU returns true if the table is the GNU/SysV variant
+func (st *SymbolTable) IsGNU() bool {
+	return st.Name == GNUSymbolTable || st.Name == GNUSymbolTable64
+}
+
+func (st *SymbolTable) Sorted() bool {
+	return strings.HasSuffix(st.Name, " SORTED")
+}
+
+// HeaderOffset returns the offset of the member header in the archive
+func (f *File) HeaderOffset() int64 {
+	_, off, _ := f.Outer()
+	return off - headerSize - f.nameSize
+}
+
+// IsSymbolTable returns true if the member is a ranlib symbol table
+func (f *File) IsSymbolTable() bool {
+	return strings.HasPrefix(f.Name, PrefixSymdef) || f.Name == GNUSymbolTable || f.Name == GNUSymbolTable64
+}
+
+// ParseSymbolTable parses the ranlib symbol table member.
+// The byte order is guessed from the size of the ranlib array since it follows the byte order of members.
+func ParseSymbolTable(f *File) (*SymbolTable, error) {
+	if !f.IsSymbolTable() {
+		return nil, fmt.Errorf("%s is not a symbol table", f.Name)
+	}
+
+	data := make([]byte, f.SectionReader.Size())
+	if _, err := f.ReadAt(data, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	st := &SymbolTable{Name: f.Name}
+	if st.IsGNU() {
+		syms, err := parseGNUSymbolTable(data, st.Is64())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		st.Symbols = syms
+		return st, nil
+	}
+
+	var err error
+	for _, bo := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		st.Symbols, err = parseRanlib(data, bo, st.Is64())
+		if err == nil {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", f.Name, err)
+}
+
+func parseRanlib(data []byte, bo binary.ByteOrder, is64 bool) ([]*Symbol, error) {
+	word, entrySize := 4, ranlibSize
+	read := func(b []byte) uint64 { return uint64(bo.Uint32(b)) }
+	if is64 {
+		word, entrySize = 8, ranlib64Size
+		read = bo.Uint64
+	}
+
+	// the size of the ranlib array and the size of the string table
+	if len(data) < 2*word {
+		return nil, errors.New("truncated symbol table")
+	}
+	ranlibsSize := read(data)
+	if ranlibsSize%uint64(entrySize) != 0 || ranlibsSize > uint64(len(data)-2*word) {
+		return nil, fmt.Errorf("invalid size of ranlib array %d", ranlibsSize)
+	}
+	ranlibs := data[word : word+int(ranlibsSize)]
+	rest := data[word+int(ranlibsSize):]
+	if len(rest) < word {
+		return nil, errors.New("truncated symbol table")
+	}
+	strSize := read(rest)
+	if strSize > uint64(len(rest)-word) {
+		return nil, fmt.Errorf("invalid size of string table %d", strSize)
+	}
+	strtab := rest[word : word+int(strSize)]
+
+	symbols := make([]*Symbol, 0, len(ranlibs)/entrySize)
+	for i := 0; i < len(ranlibs); i += entrySize {
+		strx, off := read(ranlibs[i:]), read(ranlibs[i+word:])
+		if strx >= uint64(len(strtab)) {
+			return nil, fmt.Errorf("invalid string index %d", strx)
+		}
+		name, _, _ := bytes.Cut(strtab[strx:], []byte{0})
+		symbols = append(symbols, &Symbol{Name: string(name), Offset: int64(off)})
+	}
+	return symbols, nil
+}
+
+// parseGNUSymbolTable parses the big endian count, offsets of members and NUL terminated names
+func parseGNUSymbolTable(data []byte, is64 bool) ([]*Symbol, error) {
+	word := 4
+	read := func(b []byte) uint64 { return uint64(binary.BigEndian.Uint32(b)) }
+	if is64 {
+		word = 8
+		read = binary.BigEndian.Uint64
+	}
+
+	if len(data) < word {
+		return nil, errors.New("truncated symbol table")
+	}
+	count := read(data)
+	if count > uint64(len(data)/word-1) {
+		return nil, fmt.Errorf("invalid number of symbols %d", count)
+	}
+	offsets := data[word : word+int(count)*word]
+	strtab := data[word+int(count)*word:]
+
+	symbols := make([]*Symbol, 0, count)
+	for i := 0; i < int(count); i++ {
+		name, rest, ok := bytes.Cut(strtab, []byte{0})
+		if !ok {
+			return nil, errors.New("truncated string table")
+		}
+		strtab = rest
+		symbols = append(symbols, &Symbol{Name: string(name), Offset: int64(read(offsets[i*word:]))})
+	}
+	return symbols, nil
+}
+
+// Index returns the members which define symbols
+func (st *SymbolTable) Index(files []*File) (map[string]*File, error) {
+	byOffset := map[int64]*File{}
+	for _, f := range files {
+		byOffset[f.HeaderOffset()] = f
+	}
+
+	index := map[string]*File{}
+	for _, s := range st.Symbols {
+		f, ok := byOffset[s.Offset]
+		if !ok {
+			return nil, fmt.Errorf("symbol %s refers to offset %d which is not a member", s.Name, s.Offset)
+		}
+		// the first definition is used like the linker
+		if _, ok := index[s.Name]; !ok {
+			index[s.Name] = f
+		}
+	}
+	return index, nil
+}
+
+// SymbolTableError presents differences between the symbol table and symbols of members
+type SymbolTableError struct {
+	// Missing is `symbol(member)` which is defined in a member but not in the symbol table
+	Missing []string
+	// Stale is `symbol(member)` which is in the symbol table but not defined in the member
+	Stale []string
+}
+
+func (e *SymbolTableError) Error() string {
+	msgs := []string{}
+	if len(e.Missing) > 0 {
+		msgs = append(msgs, fmt.Sprintf("missing symbols: %s", strings.Join(e.Missing, " ")))
+	}
+	if len(e.Stale) > 0 {
+		msgs = append(msgs, fmt.Sprintf("stale symbols: %s", strings.Join(e.Stale, " ")))
+	}
+	return "symbol table is out of date: " + strings.Join(msgs, ", ")
+}
+
+// ValidateSymbolTable compares the symbol table of the archive with external symbols defined in Mach-O members.
+// *SymbolTableError is returned if they are different.
+func ValidateSymbolTable(files []*File) error {
+	idx := slices.IndexFunc(files, func(f *File) bool { return f.IsSymbolTable() })
+	if idx < 0 {
+		return errors.New("no symbol table in the archive")
+	}
+	st, err := ParseSymbolTable(files[idx])
+	if err != nil {
+		return err
+	}
+
+	key := func(name string, f *File) string { return fmt.Sprintf("%s(%s)", name, f.Name) }
+	byOffset := map[int64]*File{}
+	want := map[string]struct{}{}
+	for _, f := range files {
+		if f.IsSymbolTable() {
+			continue
+		}
+		byOffset[f.HeaderOffset()] = f
+		syms, err := DefinedSymbols(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		for _, s := range syms {
+			want[key(s, f)] = struct{}{}
+		}
+	}
+
+	got := map[string]struct{}{}
+	e := &SymbolTableError{}
+	for _, s := range st.Symbols {
+		f, ok := byOffset[s.Offset]
+		if !ok {
+			e.Stale = append(e.Stale, fmt.Sprintf("%s(offset %d)", s.Name, s.Offset))
+			continue
+		}
+		k := key(s.Name, f)
+		got[k] = struct{}{}
+		if _, ok := want[k]; !ok {
+			e.Stale = append(e.Stale, k)
+		}
+	}
+	for k := range want {
+		if _, ok := got[k]; !ok {
+			e.Missing = append(e.Missing, k)
+		}
+	}
+
+	if len(e.Missing) == 0 && len(e.Stale) == 0 {
+		return nil
+	}
+	slices.Sort(e.Missing)
+	slices.Sort(e.Stale)
+	return e
+}
+
+// DefinedSymbols returns external symbols defined in the Mach-O member in the order of the symbol table.
+// Common symbols are not included like ranlib without `-c`. Nil is returned if the member is not a Mach-O file.
+func DefinedSymbols(r io.ReaderAt) ([]string, error) {
+	syms, _, err := definedSymbols(r)
+	return syms, err
+}
+
+func definedSymbols(r io.ReaderAt) ([]string, binary.ByteOrder, error) {
+	mf, err := macho.NewFile(r)
+	if err != nil {
+		fe := &macho.FormatError{}
+		if errors.As(err, &fe) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	if mf.Symtab == nil {
+		return nil, mf.ByteOrder, nil
+	}
+
+	const (
+		nStab = 0xe0
+		nType = 0x0e
+		nExt  = 0x01
+		nUndf = 0x00
+	)
+	syms := []string{}
+	for _, s := range mf.Symtab.Syms {
+		if s.Type&nStab != 0 || s.Type&nExt == 0 || s.Type&nType == nUndf {
+			continue
+		}
+		syms = append(syms, s.Name)
+	}
+	return syms, mf.ByteOrder, nil
+}
+
+// ranlibSymbol is a symbol of the member at `member` index for the symbol table written by Writer
+type ranlibSymbol struct {
+	name   string
+	member int
+}
+
+// buildSymdef returns the name and contents of the BSD symbol table or the 64-bit table if `is64` is true.
+// `offsets` are offsets of member headers in the archive. Symbols are sorted unless they have duplicates like cctools.
+func buildSymdef(symbols []*ranlibSymbol, offsets []int64, bo binary.ByteOrder, is64 bool) (string, []byte) {
+	name, sortedName := PrefixSymdef, symdefSorted
+	if is64 {
+		name, sortedName = symdef64, symdef64Sorted
+	}
+	dup := false
+	seen := map[string]struct{}{}
+	for _, s := range symbols {
+		if _, ok := seen[s.name]; ok {
+			dup = true
+			break
+		}
+		seen[s.name] = struct{}{}
+	}
+	if !dup {
+		name = sortedName
+		symbols = slices.Clone(symbols)
+		slices.SortStableFunc(symbols, func(a, b *ranlibSymbol) int { return cmp.Compare(a.name, b.name) })
+	}
+
+	strtab := &bytes.Buffer{}
+	ranlibs := &bytes.Buffer{}
+	for _, s := range symbols {
+		writeWord(ranlibs, bo, uint64(strtab.Len()), is64)
+		writeWord(ranlibs, bo, uint64(offsets[s.member]), is64)
+		strtab.WriteString(s.name)
+		strtab.WriteByte(0)
+	}
+	strtab.Write(make([]byte, padding(int64(strtab.Len()), bsdAlign)))
+
+	buf := &bytes.Buffer{}
+	writeWord(buf, bo, uint64(ranlibs.Len()), is64)
+	buf.Write(ranlibs.Bytes())
+	writeWord(buf, bo, uint64(strtab.Len()), is64)
+	buf.Write(strtab.Bytes())
+	return name, buf.Bytes()
+}
+
+// symdefSize returns the size of contents of the BSD symbol table which does not depend on offsets
+func symdefSize(symbols []*ranlibSymbol, is64 bool) int64 {
+	word, entrySize := int64(4), int64(ranlibSize)
+	if is64 {
+		word, entrySize = 8, ranlib64Size
+	}
+	strSize := int64(0)
+	for _, s := range symbols {
+		strSize += int64(len(s.name)) + 1
+	}
+	strSize += padding(strSize, bsdAlign)
+	return word + int64(len(symbols))*entrySize + word + strSize
+}
+
+// buildGNUSymbolTable returns contents of the `/` member, or the `/SYM64/` member if `is64` is true. Numbers are big endian.
+func buildGNUSymbolTable(symbols []*ranlibSymbol, offsets []int64, is64 bool) []byte {
+	buf := &bytes.Buffer{}
+	writeWord(buf, binary.BigEndian, uint64(len(symbols)), is64)
+	for _, s := range symbols {
+		writeWord(buf, binary.BigEndian, uint64(offsets[s.member]), is64)
+	}
+	for _, s := range symbols {
+		buf.WriteString(s.name)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func gnuSymbolTableSize(symbols []*ranlibSymbol, is64 bool) int64 {
+	word := int64(4)
+	if is64 {
+		word = 8
+	}
+	size := word + word*int64(len(symbols))
+	for _, s := range symbols {
+		size += int64(len(s.name)) + 1
+	}
+	return size
+}
+
+// writeWord writes `v` as 4 bytes or 8 bytes if `is64` is true
+func writeWord(buf *bytes.Buffer, bo binary.ByteOrder, v uint64, is64 bool) {
+	if is64 {
+		var b [8]byte
+		bo.PutUint64(b[:], v)
+		buf.Write(b[:])
+		return
+	}
+	writeUint32(buf, bo, uint32(v))
+}
+
+func writeUint32(buf *bytes.Buffer, bo binary.ByteOrder, v uint32) {
+	var b [4]byte
+	bo.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
@@ -0,0 +1,291 @@
+package ar_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/ar"
+)
+
+func openArchive(t *testing.T, p string) []*ar.File {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ar.NewArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestParseSymbolTable(t *testing.T) {
+	files := openArchive(t, filepath.Join("testdata", "arm64-func12.a"))
+	st, err := ar.ParseSymbolTable(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Name != "__.SYMDEF SORTED" || !st.Sorted() || st.Is64() {
+		t.Errorf("unexpected table %s", st.Name)
+	}
+	want := []*ar.Symbol{{Name: "_func1", Offset: 128}, {Name: "_func2", Offset: 928}}
+	if !reflect.DeepEqual(want, st.Symbols) {
+		t.Errorf("want %v got %v", want, st.Symbols)
+	}
+
+	index, err := st.Index(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index["_func1"].Name != "arm64-func1.o" || index["_func2"].Name != "arm64-func2.o" {
+		t.Errorf("unexpected index %v", index)
+	}
+
+	if _, err := ar.ParseSymbolTable(files[1]); err == nil {
+		t.Error("want an error for a non symbol table member")
+	}
+}
+
+func TestParseSymbolTable_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: []byte{8, 0, 0, 0, 0}},
+		{name: "truncated string table size", data: []byte{8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "oversized ranlib array", data: []byte{0xf8, 0xff, 0, 0, 0, 0, 0, 0}},
+		{name: "oversized string table", data: []byte{0, 0, 0, 0, 0xff, 0xff, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			// the GNU format does not include the padding in the size unlike the BSD format
+			w := ar.NewWriter(buf, ar.WithFormat(ar.FormatGNU))
+			if err := w.Add(&ar.Header{Name: ar.PrefixSymdef, Size: int64(len(tt.data))}, bytes.NewReader(tt.data)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			files, err := ar.NewArchive(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ar.ParseSymbolTable(files[0]); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestValidateSymbolTable(t *testing.T) {
+	for _, name := range []string{"arm64-func1.a", "arm64-func12.a", "arm64-func123.a", "amd64-func12.a"} {
+		t.Run(name, func(t *testing.T) {
+			files := openArchive(t, filepath.Join("testdata", name))
+			if err := ar.ValidateSymbolTable(files); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("stale", func(t *testing.T) {
+		regenerated := writeArchive(t, []string{"arm64-func1.o", "arm64-func2.o"}, ar.WithSymbolTable())
+		files, err := ar.NewArchive(bytes.NewReader(regenerated))
+		if err != nil {
+			t.Fatal(err)
+		}
+		symdef, err := io.ReadAll(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// swap members of the same size without updating the symbol table
+		buf := &bytes.Buffer{}
+		w := ar.NewWriter(buf)
+		if err := w.Add(&ar.Header{Name: files[0].Name, Size: int64(len(symdef))}, bytes.NewReader(symdef)); err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range []string{"arm64-func2.o", "arm64-func1.o"} {
+			addFile(t, w, obj)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err = ar.NewArchive(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ar.ValidateSymbolTable(files)
+		e := &ar.SymbolTableError{}
+		if !errors.As(err, &e) {
+			t.Fatalf("want SymbolTableError got %v", err)
+		}
+		wantMissing := []string{"_func1(arm64-func1.o)", "_func2(arm64-func2.o)"}
+		wantStale := []string{"_func1(arm64-func2.o)", "_func2(arm64-func1.o)"}
+		if !reflect.DeepEqual(wantMissing, e.Missing) || !reflect.DeepEqual(wantStale, e.Stale) {
+			t.Errorf("unexpected error %v", e)
+		}
+	})
+
+	t.Run("no symbol table", func(t *testing.T) {
+		files := openArchive(t, filepath.Join("testdata", "arm64-amd64-func12.a"))
+		if err := ar.ValidateSymbolTable(files); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
+
+func addFile(t *testing.T, w *ar.Writer, obj string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(&ar.Header{Name: obj, Size: int64(len(data)), Mode: 0100644}, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeArchive(t *testing.T, objs []string, opts ...ar.WriterOption) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w := ar.NewWriter(buf, opts...)
+	for _, obj := range objs {
+		addFile(t, w, obj)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriter_SymbolTable(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []string
+	}{
+		{name: "arm64-func12.a", objs: []string{"arm64-func1.o", "arm64-func2.o"}},
+		// duplicated symbols are not sorted
+		{name: "arm64-func123.a", objs: []string{"arm64-func1.o", "arm64-func2.o", "arm64-func3.o"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := openArchive(t, filepath.Join("testdata", tt.name))
+			data := writeArchive(t, tt.objs, ar.WithSymbolTable(), ar.WithDeterministic())
+			got, err := ar.NewArchive(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want[0].Name != got[0].Name {
+				t.Errorf("want %s got %s", want[0].Name, got[0].Name)
+			}
+			wantSymdef, _ := io.ReadAll(want[0])
+			gotSymdef, _ := io.ReadAll(got[0])
+			if !bytes.Equal(wantSymdef, gotSymdef) {
+				t.Errorf("symbol table does not match\nwant: %x\ngot:  %x", wantSymdef, gotSymdef)
+			}
+			if err := ar.ValidateSymbolTable(got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("gnu", func(t *testing.T) {
+		data := writeArchive(t, []string{"arm64-func1.o"}, ar.WithSymbolTable(), ar.WithFormat(ar.FormatGNU), ar.WithDeterministic())
+		// `/` + count 1 + offset + "_func1\0" padded to 2 bytes
+		wantHdr := "!<arch>\n/               0           0     0     0       15        `\n"
+		if got := string(data[:len(wantHdr)]); got != wantHdr {
+			t.Fatalf("\nwant: %q\ngot:  %q", wantHdr, got)
+		}
+		table := data[len(wantHdr) : len(wantHdr)+16]
+		want := append([]byte{0, 0, 0, 1, 0, 0, 0, 84}, []byte("_func1\x00\x00")...)
+		if !bytes.Equal(want, table) {
+			t.Errorf("want %q got %q", want, table)
+		}
+	})
+}
+
+// headWriter keeps the first bytes up to the capacity and counts all bytes written
+type headWriter struct {
+	head []byte
+	n    int64
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	room := cap(w.head) - len(w.head)
+	w.head = append(w.head, b[:min(room, len(b))]...)
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+func TestWriter_SymbolTable64(t *testing.T) {
+	// the member is a sparse file so that the object is placed after 4GB
+	large := filepath.Join(t.TempDir(), "large")
+	f, err := os.Create(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(1 << 32); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		format ar.Format
+		want   string
+	}{
+		{name: "bsd", format: ar.FormatBSD, want: "__.SYMDEF_64 SORTED"},
+		{name: "gnu", format: ar.FormatGNU, want: ar.GNUSymbolTable64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			hw := &headWriter{head: make([]byte, 0, 4096)}
+			w := ar.NewWriter(hw, ar.WithSymbolTable(), ar.WithFormat(tt.format), ar.WithDeterministic())
+			if err := w.Add(&ar.Header{Name: "large", Size: 1 << 32, Mode: 0100644}, f); err != nil {
+				t.Fatal(err)
+			}
+			addFile(t, w, "arm64-func1.o")
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the object is the last member
+			obj := writeArchive(t, []string{"arm64-func1.o"}, ar.WithFormat(tt.format), ar.WithDeterministic())
+			wantOffset := hw.n - int64(len(obj)-len(ar.MagicHeader))
+
+			it, err := ar.NewIter(bytes.NewReader(hw.head))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for file, err := range it.Next() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				st, err := ar.ParseSymbolTable(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if st.Name != tt.want || !st.Is64() {
+					t.Errorf("want %s got %s", tt.want, st.Name)
+				}
+				want := []*ar.Symbol{{Name: "_func1", Offset: wantOffset}}
+				if !reflect.DeepEqual(want, st.Symbols) {
+					t.Errorf("want %+v got %+v", want[0], st.Symbols[0])
+				}
+				break
+			}
+		})
+	}
+}
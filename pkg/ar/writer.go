@@ -0,0 +1,408 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a variant of the archive written by Writer
+type Format int
+
+const (
+	// FormatBSD writes every name as `#1/<len>` followed by the name and aligns members to 8 bytes like cctools and ld64 expect
+	FormatBSD Format = iota
+	// FormatGNU writes long names to the `//` table and pads members to 2 bytes
+	FormatGNU
+)
+
+const (
+	gnuNameTable      = "//"
+	gnuMaxShortName   = 15
+	bsdAlign          = 8
+	gnuAlign          = 2
+	deterministicMode = fs.FileMode(0100644)
+)
+
+type WriterOption func(w *Writer)
+
+// WithFormat specifies the variant of the archive. The default is FormatBSD.
+func WithFormat(f Format) WriterOption {
+	return func(w *Writer) {
+		w.format = f
+	}
+}
+
+// WithSymbolTable writes the symbol table of external symbols defined in Mach-O members like ranlib.
+// The table is `__.SYMDEF SORTED` or `__.SYMDEF` if symbols have duplicates for FormatBSD and `/` for FormatGNU.
+// Contents of members are read on Add to collect symbols.
+func WithSymbolTable() WriterOption {
+	return func(w *Writer) {
+		w.symbolTable = true
+	}
+}
+
+// WithDeterministic writes zero uid/gid, 0644 mode and zero mtime or `SOURCE_DATE_EPOCH` instead of values of headers
+func WithDeterministic() WriterOption {
+	return func(w *Writer) {
+		w.deterministic = true
+	}
+}
+
+type member struct {
+	hdr     Header
+	r       io.Reader
+	symbols []string
+}
+
+// Writer writes members to the archive on Close since the GNU name table precedes members
+type Writer struct {
+	w             io.Writer
+	format        Format
+	deterministic bool
+	symbolTable   bool
+	byteOrder     binary.ByteOrder
+	members       []*member
+	closed        bool
+}
+
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	aw := &Writer{w: w}
+	for _, opt := range opts {
+		opt(aw)
+	}
+	return aw
+}
+
+// Add adds the member whose contents is `hdr.Size` bytes read from `r` on Close.
+// If the symbol table is written, `r` is read in place if it is io.ReaderAt and io.Seeker like a file, otherwise it is read into memory.
+func (w *Writer) Add(hdr *Header, r io.Reader) error {
+	if w.closed {
+		return errors.New("archive writer is closed")
+	}
+	if hdr.Name == "" {
+		return errors.New("member name is empty")
+	}
+	if strings.ContainsAny(hdr.Name, "/\n") {
+		return fmt.Errorf("member name %q contains / or a newline", hdr.Name)
+	}
+	if hdr.Size < 0 {
+		return fmt.Errorf("member %s has negative size %d", hdr.Name, hdr.Size)
+	}
+
+	m := &member{hdr: *hdr, r: r}
+	if w.symbolTable {
+		sr, err := memberReader(r, hdr.Size)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", hdr.Name, err)
+		}
+		syms, bo, err := definedSymbols(sr)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", hdr.Name, err)
+		}
+		if w.byteOrder == nil {
+			w.byteOrder = bo
+		}
+		m.r, m.symbols = sr, syms
+	}
+	w.members = append(w.members, m)
+	return nil
+}
+
+// memberReader returns the reader of `size` bytes from the current offset of `r` which can be read repeatedly
+func memberReader(r io.Reader, size int64) (*io.SectionReader, error) {
+	type readSeekerAt interface {
+		io.ReaderAt
+		io.Seeker
+	}
+	if rs, ok := r.(readSeekerAt); ok {
+		off, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(rs, off, size), nil
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(bytes.NewReader(data), 0, size), nil
+}
+
+// Close writes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	mtime, err := w.modTime()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(MagicHeader); err != nil {
+		return err
+	}
+
+	table := w.gnuNameTable()
+	if w.symbolTable {
+		if err := w.writeSymbolTable(mtime, table); err != nil {
+			return err
+		}
+	}
+
+	if w.format == FormatGNU && table.Len() > 0 {
+		hdr := &Header{Name: gnuNameTable, Size: int64(table.Len())}
+		if err := writeHeader(w.w, hdr.Name, hdr, hdr.Size, true); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w.w, table.String()); err != nil {
+			return err
+		}
+		if err := writePadding(w.w, padding(hdr.Size, gnuAlign), '\n'); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range w.members {
+		hdr := m.hdr
+		if w.deterministic {
+			hdr.ModTime, hdr.UID, hdr.GID, hdr.Mode = mtime, 0, 0, deterministicMode
+		}
+		if err := w.writeMember(&hdr, m.r, table.offsets); err != nil {
+			return fmt.Errorf("member %s: %w", m.hdr.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeSymbolTable writes the symbol table member which precedes the GNU name table and members
+func (w *Writer) writeSymbolTable(mtime time.Time, table *nameTable) error {
+	symbols := []*ranlibSymbol{}
+	for i, m := range w.members {
+		for _, s := range m.symbols {
+			symbols = append(symbols, &ranlibSymbol{name: s, member: i})
+		}
+	}
+
+	if w.format == FormatGNU {
+		offsets, is64 := w.symbolTableOffsets(func(is64 bool) int64 {
+			size := gnuSymbolTableSize(symbols, is64)
+			cur := int64(len(MagicHeader)) + headerSize + size + padding(size, gnuAlign)
+			if table.Len() > 0 {
+				cur += headerSize + int64(table.Len()) + padding(int64(table.Len()), gnuAlign)
+			}
+			return cur
+		})
+		name := GNUSymbolTable
+		if is64 {
+			name = GNUSymbolTable64
+		}
+		data := buildGNUSymbolTable(symbols, offsets, is64)
+		if err := writeHeader(w.w, name, &Header{ModTime: time.Unix(0, 0)}, int64(len(data)), false); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(data); err != nil {
+			return err
+		}
+		return writePadding(w.w, padding(int64(len(data)), gnuAlign), 0)
+	}
+
+	bo := w.byteOrder
+	if bo == nil {
+		bo = binary.LittleEndian
+	}
+	offsets, is64 := w.symbolTableOffsets(func(is64 bool) int64 {
+		name, _ := buildSymdef(symbols, make([]int64, len(w.members)), bo, is64)
+		return int64(len(MagicHeader)) + w.memberSize(name, symdefSize(symbols, is64), nil)
+	})
+	name, data := buildSymdef(symbols, offsets, bo, is64)
+
+	hdr := &Header{Name: name, Size: int64(len(data)), ModTime: mtime, Mode: deterministicMode}
+	if !w.deterministic {
+		hdr.ModTime, hdr.UID, hdr.GID = time.Now(), os.Getuid(), os.Getgid()
+	}
+	return w.writeMember(hdr, bytes.NewReader(data), nil)
+}
+
+// symbolTableOffsets returns offsets of member headers following the symbol table and whether the 64-bit table is required.
+// `start` returns the offset of the first member after the 32-bit or 64-bit table.
+func (w *Writer) symbolTableOffsets(start func(is64 bool) int64) ([]int64, bool) {
+	offsets := w.memberOffsets(start(false), nil)
+	if len(offsets) == 0 || offsets[len(offsets)-1] <= math.MaxUint32 {
+		return offsets, false
+	}
+	return w.memberOffsets(start(true), nil), true
+}
+
+// memberOffsets returns offsets of member headers when the first member starts at `cur`
+func (w *Writer) memberOffsets(cur int64, offsets map[string]int) []int64 {
+	ret := make([]int64, len(w.members))
+	for i, m := range w.members {
+		ret[i] = cur
+		cur += w.memberSize(m.hdr.Name, m.hdr.Size, offsets)
+	}
+	return ret
+}
+
+// memberSize returns the size of the member including the header and padding
+func (w *Writer) memberSize(name string, size int64, offsets map[string]int) int64 {
+	if w.format == FormatGNU {
+		return headerSize + size + padding(size, gnuAlign)
+	}
+	return headerSize + bsdNameSize(name) + size + padding(size, bsdAlign)
+}
+
+// bsdNameSize returns the size of the name padded with NULs so that contents start at an 8 bytes boundary.
+// `__.SYMDEF` and `__.SYMDEF_64` are padded to the size of the sorted names like cctools.
+func bsdNameSize(name string) int64 {
+	switch name {
+	case PrefixSymdef:
+		name = symdefSorted
+	case symdef64:
+		name = symdef64Sorted
+	}
+	nameSize := int64(len(name))
+	return nameSize + padding(headerSize+nameSize, bsdAlign)
+}
+
+// modTime returns SOURCE_DATE_EPOCH or zero time for deterministic archives
+func (w *Writer) modTime() (time.Time, error) {
+	v, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !w.deterministic || !ok || v == "" {
+		return time.Unix(0, 0), nil
+	}
+	sec, err := parseDecimal(v)
+	if err != nil || sec < 0 {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %s", v)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+type nameTable struct {
+	strings.Builder
+	offsets map[string]int
+}
+
+// gnuNameTable returns names longer than 15 bytes and offsets of them in the table for FormatGNU
+func (w *Writer) gnuNameTable() *nameTable {
+	table := &nameTable{offsets: map[string]int{}}
+	if w.format != FormatGNU {
+		return table
+	}
+	for _, m := range w.members {
+		name := m.hdr.Name
+		if len(name) <= gnuMaxShortName {
+			continue
+		}
+		if _, ok := table.offsets[name]; ok {
+			continue
+		}
+		table.offsets[name] = table.Len()
+		table.WriteString(name + "/\n")
+	}
+	return table
+}
+
+func (w *Writer) writeMember(hdr *Header, r io.Reader, offsets map[string]int) error {
+	switch w.format {
+	case FormatGNU:
+		name := hdr.Name + "/"
+		if off, ok := offsets[hdr.Name]; ok {
+			name = "/" + strconv.Itoa(off)
+		}
+		if err := writeHeader(w.w, name, hdr, hdr.Size, false); err != nil {
+			return err
+		}
+		if err := copyMember(w.w, r, hdr.Size); err != nil {
+			return err
+		}
+		return writePadding(w.w, padding(hdr.Size, gnuAlign), '\n')
+	case FormatBSD:
+		nameSize := bsdNameSize(hdr.Name)
+		// the padding of contents is a part of the member like llvm-ar
+		pad := padding(hdr.Size, bsdAlign)
+		if err := writeHeader(w.w, bsdVariantMarker+strconv.FormatInt(nameSize, 10), hdr, nameSize+hdr.Size+pad, false); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w.w, hdr.Name); err != nil {
+			return err
+		}
+		if err := writePadding(w.w, nameSize-int64(len(hdr.Name)), 0); err != nil {
+			return err
+		}
+		if err := copyMember(w.w, r, hdr.Size); err != nil {
+			return err
+		}
+		return writePadding(w.w, pad, '\n')
+	default:
+		return fmt.Errorf("unknown format %d", w.format)
+	}
+}
+
+// writeHeader writes the fixed size header. Fields other than the name and the size are blank if `special` is true.
+func writeHeader(w io.Writer, name string, hdr *Header, size int64, special bool) error {
+	fields := []struct {
+		v     string
+		width int
+		field string
+	}{
+		{name, 16, "name"},
+		{strconv.FormatInt(hdr.ModTime.Unix(), 10), 12, "modtime"},
+		{strconv.Itoa(hdr.UID), 6, "uid"},
+		{strconv.Itoa(hdr.GID), 6, "gid"},
+		{strconv.FormatUint(uint64(hdr.Mode), 8), 8, "mode"},
+		{strconv.FormatInt(size, 10), 10, "size"},
+	}
+	if special {
+		for i := 1; i < 5; i++ {
+			fields[i].v = ""
+		}
+	}
+
+	buf := make([]byte, 0, headerSize)
+	for _, f := range fields {
+		if len(f.v) > f.width {
+			return fmt.Errorf("%s %s does not fit in %d bytes", f.field, f.v, f.width)
+		}
+		buf = append(buf, f.v...)
+		buf = append(buf, strings.Repeat(" ", f.width-len(f.v))...)
+	}
+	buf = append(buf, 0x60, 0x0a)
+	_, err := w.Write(buf)
+	return err
+}
+
+func copyMember(w io.Writer, r io.Reader, size int64) error {
+	n, err := io.CopyN(w, r, size)
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("contents is %d bytes shorter than the size %d", size-n, size)
+	}
+	return err
+}
+
+func padding(size int64, align int64) int64 {
+	return (align - size%align) % align
+}
+
+func writePadding(w io.Writer, n int64, c byte) error {
+	if n == 0 {
+		return nil
+	}
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = c
+	}
+	_, err := w.Write(buf)
+	return err
+}
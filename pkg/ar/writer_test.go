@@ -0,0 +1,180 @@
+package ar_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konoui/lipo/pkg/ar"
+)
+
+func TestWriter_BSD(t *testing.T) {
+	objs := []string{"arm64-func1.o", "arm64-func2.o", "amd64-func1.o"}
+	names := []string{"arm64-func1.o", "a-very-long-member-name.o", "with space.o"}
+	contents := [][]byte{}
+
+	buf := &bytes.Buffer{}
+	w := ar.NewWriter(buf)
+	mtime := time.Unix(1700000000, 0)
+	for i, obj := range objs {
+		data, err := os.ReadFile(filepath.Join("testdata", obj))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, data)
+		hdr := &ar.Header{Name: names[i], Size: int64(len(data)), ModTime: mtime, UID: 501, GID: 20, Mode: 0100644}
+		if err := w.Add(hdr, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// odd size contents are padded with newlines
+	odd := []byte("odd")
+	if err := w.Add(&ar.Header{Name: "odd.txt", Size: int64(len(odd)), Mode: 0644}, bytes.NewReader(odd)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ar.NewArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("want 4 members got %d", len(files))
+	}
+	for i, f := range files[:3] {
+		if f.Name != names[i] || f.UID != 501 || f.GID != 20 || f.Mode != 0100644 || !f.ModTime.Equal(mtime) {
+			t.Errorf("unexpected header %+v", f.Header)
+		}
+		got, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(contents[i], got) {
+			t.Errorf("%s: contents do not match", f.Name)
+		}
+		_, off, _ := f.Outer()
+		if off%8 != 0 {
+			t.Errorf("%s: contents are not aligned to 8 bytes: %d", f.Name, off)
+		}
+	}
+	got, err := io.ReadAll(files[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "odd\n\n\n\n\n"; string(got) != want {
+		t.Errorf("want %q got %q", want, string(got))
+	}
+}
+
+func TestWriter_Deterministic(t *testing.T) {
+	tests := []struct {
+		name  string
+		epoch string
+		want  time.Time
+	}{
+		{name: "zero", want: time.Unix(0, 0)},
+		{name: "SOURCE_DATE_EPOCH", epoch: "1600000000", want: time.Unix(1600000000, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+			write := func(mtime time.Time) []byte {
+				buf := &bytes.Buffer{}
+				w := ar.NewWriter(buf, ar.WithDeterministic())
+				hdr := &ar.Header{Name: "a.o", Size: 8, ModTime: mtime, UID: 501, GID: 20, Mode: 0755}
+				if err := w.Add(hdr, strings.NewReader("12345678")); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			}
+
+			// headers of members are ignored
+			first := write(time.Unix(1, 0))
+			if !bytes.Equal(first, write(time.Unix(2, 0))) {
+				t.Error("archives are not deterministic")
+			}
+
+			files, err := ar.NewArchive(bytes.NewReader(first))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := files[0].Header
+			if h.UID != 0 || h.GID != 0 || h.Mode != fs.FileMode(0100644) || !h.ModTime.Equal(tt.want) {
+				t.Errorf("unexpected header %+v", h)
+			}
+		})
+	}
+
+	t.Run("invalid SOURCE_DATE_EPOCH", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "invalid")
+		w := ar.NewWriter(io.Discard, ar.WithDeterministic())
+		if err := w.Close(); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
+
+func TestWriter_GNU(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := ar.NewWriter(buf, ar.WithFormat(ar.FormatGNU), ar.WithDeterministic())
+	members := []struct{ name, data string }{
+		{"short.o", "abc"},
+		{"a-very-long-member-name.o", "abcd"},
+	}
+	for _, m := range members {
+		if err := w.Add(&ar.Header{Name: m.name, Size: int64(len(m.data))}, strings.NewReader(m.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "!<arch>\n" +
+		"//                                              27        `\n" +
+		"a-very-long-member-name.o/\n\n" +
+		"short.o/        0           0     0     100644  3         `\n" +
+		"abc\n" +
+		"/0              0           0     0     100644  4         `\n" +
+		"abcd"
+	if got := buf.String(); want != got {
+		t.Errorf("\nwant:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func TestWriter_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  *ar.Header
+		r    io.Reader
+	}{
+		{name: "short contents", hdr: &ar.Header{Name: "a.o", Size: 10}, r: strings.NewReader("abc")},
+		{name: "too large uid", hdr: &ar.Header{Name: "a.o", Size: 3, UID: 10000000}, r: strings.NewReader("abc")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ar.NewWriter(io.Discard)
+			if err := w.Add(tt.hdr, tt.r); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+
+	w := ar.NewWriter(io.Discard)
+	if err := w.Add(&ar.Header{Name: "dir/a.o"}, strings.NewReader("")); err == nil {
+		t.Error("want an error for a name containing /")
+	}
+}
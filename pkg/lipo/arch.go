@@ -5,17 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"strings"
+	"sync"
 
 	"github.com/konoui/lipo/pkg/ar"
 	"github.com/konoui/lipo/pkg/lmacho"
-	"github.com/konoui/lipo/pkg/util"
 )
 
 type FatFile struct {
 	lmacho.FatHeader
-	Arches []Arch
+	Arches   []Arch
+	Warnings []string
 	io.Closer
 }
 
@@ -89,6 +88,11 @@ type arch struct {
 	updatedAlign uint32
 }
 
+// Outer returns the object as the underlying reader so that lmacho can find the file of the object
+func (a *arch) Outer() (io.ReaderAt, int64, int64) {
+	return a.Object, 0, int64(a.Size())
+}
+
 func (a *arch) Name() string {
 	return a.name
 }
@@ -113,8 +117,8 @@ func close[T io.Closer](arches ...T) {
 	}
 }
 
-func OpenFatFile(p string) (*FatFile, error) {
-	f, err := os.Open(p)
+func (l *Lipo) openFatFile(p string) (*FatFile, error) {
+	f, err := l.open(p)
 	if err != nil {
 		return nil, err
 	}
@@ -137,83 +141,169 @@ func OpenFatFile(p string) (*FatFile, error) {
 	return &FatFile{
 		Arches:    arches,
 		FatHeader: ff.FatHeader,
+		Warnings:  ff.Warnings,
 		Closer:    f,
 	}, nil
 }
 
+func OpenFatFile(p string) (*FatFile, error) {
+	return New().openFatFile(p)
+}
+
 func OpenArches(inputs []*ArchInput) ([]Arch, error) {
+	return New().openArches(inputs, MergeError)
+}
+
+func OpenArchive(p string) (*Archive, error) {
+	return New().openArchive(p)
+}
+
+func (l *Lipo) openArches(inputs []*ArchInput, policy MergePolicy) (_ []Arch, err error) {
 	arches := make([]Arch, 0, len(inputs))
-	for _, input := range inputs {
-		f, err := os.Open(input.Bin)
+	defer func() {
 		if err != nil {
-			return nil, err
-		}
-		stats, err := f.Stat()
-		if err != nil {
-			return nil, err
+			close(arches...)
 		}
+	}()
 
-		typ, err := inspect(input.Bin)
+	for _, input := range inputs {
+		if input.Source != nil {
+			l.addSources(input.Source)
+		}
+		name := input.name()
+		typ, err := l.inspect(name)
 		if err != nil {
 			return nil, err
 		}
 
 		switch typ {
 		case inspectThin:
-			sr := io.NewSectionReader(f, 0, stats.Size())
-			obj, err := lmacho.NewArch(sr)
+			a, err := l.openThin(name)
 			if err != nil {
-				fe := &lmacho.FormatError{}
-				if errors.As(err, &fe) {
-					return nil, fmt.Errorf("can't figure out the architecture type of: %s", input.Bin)
-				}
 				return nil, err
 			}
-			if input.Arch != "" {
-				if obj.CPUString() != input.Arch {
-					return nil, fmt.Errorf("specified architecture: %s for input file: %s does not match the file's architecture", input.Arch, input.Bin)
-				}
+			arches = append(arches, a)
+			if input.Arch != "" && a.CPUString() != input.Arch {
+				return nil, fmt.Errorf("specified architecture: %s for input file: %s does not match the file's architecture", input.Arch, name)
 			}
-			arches = append(arches, &arch{
-				Object:       obj,
-				name:         input.Bin,
-				updatedAlign: obj.Align(),
-				Closer:       f,
-			})
 		case inspectArchive:
-			archive, err := OpenArchive(input.Bin)
+			archive, err := l.openArchive(name)
 			if err != nil {
 				return nil, err
 			}
 			arches = append(arches, archive)
+			if input.Arch != "" && archive.CPUString() != input.Arch {
+				return nil, fmt.Errorf("specified architecture: %s for input file: %s does not match the file's architecture", input.Arch, name)
+			}
 		case inspectFat:
-			fat, err := OpenFatFile(input.Bin)
+			fat, err := l.openFatFile(name)
 			if err != nil {
 				return nil, err
 			}
-			arches = append(arches, fat.Arches...)
+			if input.Arch == "" {
+				// the fat file is closed when all of the arches are closed
+				shared := &sharedCloser{c: fat.Closer}
+				for _, a := range fat.Arches {
+					a.(*arch).Closer = shared.ref()
+				}
+				arches = append(arches, fat.Arches...)
+				continue
+			}
+
+			// select the specified architecture from the fat file
+			extracted := extract(fat.Arches, input.Arch)
+			if len(extracted) == 0 {
+				fat.Close()
+				return nil, fmt.Errorf("fat input file (%s) does not contain the specified architecture (%s)", name, input.Arch)
+			}
+			selected := extracted[0].(*arch)
+			selected.Closer = fat.Closer
+			arches = append(arches, selected)
 		default:
-			return nil, fmt.Errorf("can't figure out the architecture type of: %s", input.Bin)
+			return nil, fmt.Errorf("can't figure out the architecture type of: %s", name)
 		}
 
 	}
 
-	dup := util.Duplicates(arches, func(a Arch) string {
-		return a.CPUString()
-	})
-	if dup != nil {
-		return nil, fmt.Errorf("the inputs have the same architectures (%s)", *dup)
+	return mergeArches(arches, policy)
+}
+
+func (l *Lipo) openThin(name string) (Arch, error) {
+	f, err := l.open(name)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	sr := io.NewSectionReader(f, 0, stats.Size())
+	obj, err := lmacho.NewArch(sr)
+	if err != nil {
+		f.Close()
+		fe := &lmacho.FormatError{}
+		if errors.As(err, &fe) {
+			return nil, fmt.Errorf("can't figure out the architecture type of: %s", name)
+		}
+		return nil, err
 	}
+	return &arch{
+		Object:       obj,
+		name:         name,
+		updatedAlign: obj.Align(),
+		Closer:       f,
+	}, nil
+}
 
-	return arches, nil
+// sharedCloser closes the underlying closer when all of the references are closed
+type sharedCloser struct {
+	c    io.Closer
+	mu   sync.Mutex
+	refs int
 }
 
-func OpenArchive(p string) (*Archive, error) {
-	ra, err := os.Open(p)
+func (s *sharedCloser) ref() io.Closer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs++
+	return &sharedRef{s: s}
+}
+
+type sharedRef struct {
+	s    *sharedCloser
+	once sync.Once
+}
+
+func (r *sharedRef) Close() (err error) {
+	r.once.Do(func() {
+		r.s.mu.Lock()
+		defer r.s.mu.Unlock()
+		r.s.refs--
+		if r.s.refs == 0 {
+			err = r.s.c.Close()
+		}
+	})
+	return err
+}
+
+func (l *Lipo) openArchive(p string) (*Archive, error) {
+	ra, err := l.open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := newArchive(ra, p)
 	if err != nil {
+		ra.Close()
 		return nil, err
 	}
+	return archive, nil
+}
 
+// newArchive reads the archive from the opened file. The archive closes the file.
+func newArchive(ra file, p string) (*Archive, error) {
 	info, err := ra.Stat()
 	if err != nil {
 		return nil, err
@@ -228,13 +318,13 @@ func OpenArchive(p string) (*Archive, error) {
 
 	arches := make([]Arch, 0, len(files))
 	for _, f := range files {
-		if strings.HasPrefix(f.Name, ar.PrefixSymdef) {
+		if f.IsSymbolTable() {
 			continue
 		}
 
 		m, err := lmacho.NewArch(f.SectionReader)
 		if err != nil {
-			typ, _ := inspect(p)
+			typ, _ := inspectFile(ra, p)
 			if typ == inspectFat {
 				return nil, &lmacho.FormatError{Err: fmt.Errorf("archive member %s(%s) is a fat file (not allowed in an archive", ra.Name(), f.Name)}
 			}
@@ -1,53 +1,110 @@
 package lipo
 
 import (
+	"errors"
 	"fmt"
+	"io"
+
+	"github.com/konoui/lipo/pkg/lmacho"
 )
 
+// Archs returns the architectures of the input.
+// The architectures of every member are returned in the order of appearance if the input is a zip file.
 func (l *Lipo) Archs() ([]string, error) {
+	all, err := l.archsAll()
+	if err != nil {
+		return nil, err
+	}
+
+	cpus := []string{}
+	seen := map[string]struct{}{}
+	for _, c := range all {
+		for _, cpu := range c {
+			if _, ok := seen[cpu]; !ok {
+				seen[cpu] = struct{}{}
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// archsAll returns the architectures of the input or of every member if the input is a zip file
+func (l *Lipo) archsAll() ([][]string, error) {
 	if err := validateOneInput(l.in); err != nil {
 		return nil, err
 	}
 
-	bin := l.in[0]
-	cpus, _, err := archs(bin)
-	return cpus, err
+	in, err := l.expandZips(l.in)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([][]string, len(in))
+	for i, bin := range in {
+		cpus, _, err := l.archs(bin)
+		if err != nil {
+			return nil, err
+		}
+		all[i] = cpus
+	}
+	return all, nil
 }
 
-func archs(bin string) ([]string, inspectType, error) {
-	typ, err := inspect(bin)
+// archs opens the file once to inspect it and to read architectures
+func (l *Lipo) archs(bin string) ([]string, inspectType, error) {
+	f, err := l.open(bin)
+	if err != nil {
+		return nil, inspectUnknown, err
+	}
+	defer f.Close()
+
+	typ, err := inspectFile(f, bin)
 	if err != nil {
 		return nil, inspectUnknown, err
 	}
 
+	cpus, err := archsFile(f, bin, typ)
+	return cpus, typ, err
+}
+
+// archsFile reads architectures of the opened file of the inspected type
+func archsFile(f file, bin string, typ inspectType) ([]string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
 	switch typ {
 	case inspectThin:
-		objs, err := OpenArches([]*ArchInput{{Bin: bin}})
+		obj, err := lmacho.NewArch(io.NewSectionReader(f, 0, info.Size()))
 		if err != nil {
-			return nil, typ, err
+			fe := &lmacho.FormatError{}
+			if errors.As(err, &fe) {
+				return nil, fmt.Errorf("can't figure out the architecture type of: %s", bin)
+			}
+			return nil, err
 		}
-		defer close(objs...)
-		return []string{objs[0].CPUString()}, inspectThin, nil
+		return []string{obj.CPUString()}, nil
 	case inspectArchive:
-		archive, err := OpenArchive(bin)
+		// the file is closed by the caller instead of the archive
+		archive, err := newArchive(f, bin)
 		if err != nil {
-			return nil, typ, err
+			return nil, err
 		}
-		defer archive.Close()
-		return []string{archive.Arches[0].CPUString()}, typ, nil
+		return []string{archive.Arches[0].CPUString()}, nil
 	case inspectFat:
-		fat, err := OpenFatFile(bin)
+		ff, err := lmacho.NewFatFile(f)
 		if err != nil {
-			return nil, typ, fmt.Errorf("internal error: %w", err)
+			return nil, fmt.Errorf("internal error: %w", err)
 		}
-		defer fat.Close()
 
-		cpus := make([]string, len(fat.Arches))
+		cpus := make([]string, len(ff.Arches))
 		for i := range cpus {
-			cpus[i] = fat.Arches[i].CPUString()
+			cpus[i] = ff.Arches[i].CPUString()
 		}
-		return cpus, typ, nil
+		return cpus, nil
 	default:
-		return nil, inspectUnknown, fmt.Errorf("unexpected type: %d", typ)
+		return nil, fmt.Errorf("unexpected type: %d", typ)
 	}
 }
@@ -0,0 +1,174 @@
+package lipo
+
+import (
+	"cmp"
+	"context"
+	"debug/macho"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+// ArtifactBundleInfoName is a file name of the manifest of an artifact bundle
+const ArtifactBundleInfoName = "info.json"
+
+// ArtifactBundleInfo presents info.json of a Swift Package Manager artifact bundle
+// https://github.com/swiftlang/swift-evolution/blob/main/proposals/0305-swiftpm-binary-target-improvements.md
+type ArtifactBundleInfo struct {
+	SchemaVersion string               `json:"schemaVersion"`
+	Artifacts     map[string]*Artifact `json:"artifacts"`
+}
+
+type Artifact struct {
+	Version  string             `json:"version"`
+	Type     string             `json:"type"`
+	Variants []*ArtifactVariant `json:"variants"`
+}
+
+type ArtifactVariant struct {
+	Path             string   `json:"path"`
+	SupportedTriples []string `json:"supportedTriples"`
+}
+
+type artifactSlice struct {
+	arch   Arch
+	triple string
+}
+
+// CreateArtifactBundle creates the artifact bundle directory of the output from the thin or fat executables of the inputs.
+// Every slice becomes a variant of the target triple. If `universal` is true, macOS slices are merged into a fat file as a variant.
+func (l *Lipo) CreateArtifactBundle(name, version string, universal bool) (*ArtifactBundleInfo, error) {
+	return l.CreateArtifactBundleContext(context.Background(), name, version, universal)
+}
+
+// CreateArtifactBundleContext is CreateArtifactBundle which stops writing the output when the context is done
+func (l *Lipo) CreateArtifactBundleContext(ctx context.Context, name, version string, universal bool) (_ *ArtifactBundleInfo, err error) {
+	if len(l.in) == 0 {
+		return nil, errNoInput
+	}
+	if name == "" {
+		name = filepath.Base(l.in[0])
+	}
+	if version == "" {
+		return nil, fmt.Errorf("version of %s is not specified", name)
+	}
+	if filepath.Ext(l.out) != ".artifactbundle" {
+		return nil, fmt.Errorf("output %s must have .artifactbundle extension", l.out)
+	}
+	if _, err := os.Lstat(l.out); err == nil {
+		return nil, fmt.Errorf("output %s already exists", l.out)
+	}
+
+	// open inputs one by one since the same architecture can be built for different platforms
+	arches := []Arch{}
+	defer func() { close(arches...) }()
+	for _, in := range l.in {
+		opened, err := l.openArches([]*ArchInput{{Bin: in}}, MergeError)
+		if err != nil {
+			return nil, err
+		}
+		arches = append(arches, opened...)
+	}
+
+	variants := map[string][]*artifactSlice{}
+	seen := map[string]string{}
+	for _, a := range arches {
+		mf, err := archMachO(a)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", a.Name(), a.CPUString(), err)
+		}
+		if mf.Type != macho.TypeExec {
+			return nil, fmt.Errorf("%s (%s) is not an executable", a.Name(), a.CPUString())
+		}
+		p := lmacho.BuildPlatform(mf)
+		triple, ok := lmacho.Triple(a.CPUString(), p)
+		if !ok {
+			return nil, fmt.Errorf("%s (%s): unsupported platform %s", a.Name(), a.CPUString(), p)
+		}
+		if prev, ok := seen[triple]; ok {
+			return nil, fmt.Errorf("%s and %s have the same target triple (%s)", prev, a.Name(), triple)
+		}
+		seen[triple] = a.Name()
+
+		dir := fmt.Sprintf("%s-%s-%s", name, version, triple)
+		if universal && p == lmacho.PlatformMacOS {
+			dir = fmt.Sprintf("%s-%s-macos", name, version)
+		}
+		variants[dir] = append(variants[dir], &artifactSlice{arch: a, triple: triple})
+	}
+
+	if err := os.MkdirAll(l.out, 0755); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// do not leave the incomplete bundle on failure
+		if err != nil {
+			os.RemoveAll(l.out)
+		}
+	}()
+
+	artifact := &Artifact{Version: version, Type: "executable", Variants: make([]*ArtifactVariant, 0, len(variants))}
+	for dir, ss := range variants {
+		v := &ArtifactVariant{Path: filepath.Join(dir, "bin", name)}
+		for _, s := range ss {
+			v.SupportedTriples = append(v.SupportedTriples, s.triple)
+		}
+		if err := l.writeArtifactVariant(ctx, filepath.Join(l.out, v.Path), ss); err != nil {
+			return nil, err
+		}
+		artifact.Variants = append(artifact.Variants, v)
+	}
+	slices.SortFunc(artifact.Variants, func(a, b *ArtifactVariant) int { return cmp.Compare(a.Path, b.Path) })
+
+	info := &ArtifactBundleInfo{
+		SchemaVersion: "1.0",
+		Artifacts:     map[string]*Artifact{name: artifact},
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(l.out, ArtifactBundleInfoName), data, 0644); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// writeArtifactVariant writes the single slice as a thin file or slices as a fat file
+func (l *Lipo) writeArtifactVariant(ctx context.Context, p string, ss []*artifactSlice) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// use the permission of the first input
+	perm, err := l.perm(ss[0].arch.Name())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if len(ss) == 1 {
+		if err := lmacho.CopyObjectContext(ctx, f, ss[0].arch, l.progress); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	objects := make([]Arch, len(ss))
+	for i, s := range ss {
+		objects[i] = s.arch
+	}
+	if err := lmacho.CreateFatContext(ctx, f, objects, false, false, lmacho.WithProgress(l.progress)); err != nil {
+		return err
+	}
+	return f.Close()
+}
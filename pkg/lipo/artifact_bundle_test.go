@@ -0,0 +1,108 @@
+package lipo_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_CreateArtifactBundle(t *testing.T) {
+	t.Run("universal", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		ios := patchedLib(t, p, filepath.Join(dir, "ios"), "arm64", lmacho.PlatformIOS)
+
+		out := filepath.Join(dir, "tool.artifactbundle")
+		info, err := lipo.New(lipo.WithInputs(p.FatBin, ios), lipo.WithOutput(out)).CreateArtifactBundle("tool", "1.0.0", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := &lipo.ArtifactBundleInfo{
+			SchemaVersion: "1.0",
+			Artifacts: map[string]*lipo.Artifact{
+				"tool": {
+					Version: "1.0.0",
+					Type:    "executable",
+					Variants: []*lipo.ArtifactVariant{
+						{Path: "tool-1.0.0-arm64-apple-ios/bin/tool", SupportedTriples: []string{"arm64-apple-ios"}},
+						{Path: "tool-1.0.0-macos/bin/tool", SupportedTriples: []string{"x86_64-apple-macosx", "arm64-apple-macosx"}},
+					},
+				},
+			},
+		}
+		if !reflect.DeepEqual(want, info) {
+			t.Errorf("unexpected info %+v", info.Artifacts["tool"].Variants)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, lipo.ArtifactBundleInfoName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := &lipo.ArtifactBundleInfo{}
+		if err := json.Unmarshal(data, got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("unexpected %s\n%s", lipo.ArtifactBundleInfoName, string(data))
+		}
+
+		wantFat := filepath.Join(p.Dir, wantName(t))
+		p.Create(t, wantFat, p.Bin(t, "arm64"), p.Bin(t, "x86_64"))
+		testlipo.DiffSha256(t, wantFat, filepath.Join(out, "tool-1.0.0-macos", "bin", "tool"))
+		testlipo.DiffSha256(t, ios, filepath.Join(out, "tool-1.0.0-arm64-apple-ios", "bin", "tool"))
+	})
+
+	t.Run("thin-variants", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		out := filepath.Join(t.TempDir(), "tool.artifactbundle")
+		info, err := lipo.New(lipo.WithInputs(p.FatBin), lipo.WithOutput(out)).CreateArtifactBundle("tool", "1.0.0", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		variants := info.Artifacts["tool"].Variants
+		if len(variants) != 2 {
+			t.Fatalf("want 2 variants got %d", len(variants))
+		}
+		for _, v := range variants {
+			arch, _, _ := strings.Cut(v.SupportedTriples[0], "-")
+			testlipo.DiffSha256(t, p.Bin(t, arch), filepath.Join(out, v.Path))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		obj := testlipo.Setup(t, bm, []string{"obj_arm64"})
+		dir := t.TempDir()
+		tests := []struct {
+			name    string
+			inputs  []string
+			version string
+			want    string
+		}{
+			{name: "object", inputs: []string{obj.Bin(t, "obj_arm64")}, version: "1.0.0", want: "is not an executable"},
+			{name: "same triple", inputs: []string{p.FatBin, p.Bin(t, "arm64")}, version: "1.0.0", want: "same target triple (arm64-apple-macosx)"},
+			{name: "no version", inputs: []string{p.FatBin}, want: "version of tool is not specified"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				out := filepath.Join(dir, "tool.artifactbundle")
+				_, err := lipo.New(lipo.WithInputs(tt.inputs...), lipo.WithOutput(out)).CreateArtifactBundle("tool", tt.version, true)
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("want %s got %v", tt.want, err)
+				}
+				if _, err := os.Stat(out); err == nil {
+					t.Errorf("output should not be created")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package lipo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/util"
+)
+
+// FatBuilder edits architectures of a fat file in memory and writes the result once by Commit.
+// Architectures refer to slices of the inputs, so the inputs are read on Commit.
+type FatBuilder struct {
+	l      *Lipo
+	arches []Arch
+	hidden map[string]struct{}
+	perm   fs.FileMode
+	opened []Arch
+	fats   []*FatFile
+}
+
+// NewFatBuilder returns a builder without architectures.
+// The options are used to open inputs and to write the output on Commit.
+func NewFatBuilder(opts ...Option) *FatBuilder {
+	return &FatBuilder{
+		l:      New(opts...),
+		hidden: map[string]struct{}{},
+		perm:   defaultPerm,
+	}
+}
+
+// LoadFatBuilder returns a builder with architectures of the fat file.
+// The fat magic, hidden architectures and the permission of the fat file are kept.
+func LoadFatBuilder(fatBin string, opts ...Option) (*FatBuilder, error) {
+	b := NewFatBuilder(opts...)
+	perm, err := b.l.perm(fatBin)
+	if err != nil {
+		return nil, err
+	}
+
+	ff, err := b.l.openFatFile(fatBin)
+	if err != nil {
+		return nil, err
+	}
+	b.fats = append(b.fats, ff)
+
+	for _, a := range ff.Arches {
+		if a.(*arch).Object.(*lmacho.FatArch).Hidden {
+			b.hidden[a.CPUString()] = struct{}{}
+		}
+	}
+	b.arches = append(b.arches, ff.Arches...)
+	b.l.fat64 = ff.Magic == lmacho.MagicFat64
+	b.perm = perm
+	return b, nil
+}
+
+// Arches returns the current architectures
+func (b *FatBuilder) Arches() []Arch {
+	return append([]Arch{}, b.arches...)
+}
+
+// Add adds architectures of the inputs. The same architectures as existing ones are not allowed.
+// The permission of the output is taken from the last input.
+func (b *FatBuilder) Add(inputs ...*ArchInput) error {
+	if len(inputs) == 0 {
+		return errNoInput
+	}
+	arches, err := b.l.openArches(inputs, MergeError)
+	if err != nil {
+		return err
+	}
+	b.opened = append(b.opened, arches...)
+
+	if found := extract(b.arches, cpuStrings(arches)...); len(found) > 0 {
+		return fmt.Errorf("the inputs have the same architectures (%s)", found[0].CPUString())
+	}
+
+	perm, err := b.l.perm(arches[len(arches)-1].Name())
+	if err != nil {
+		return err
+	}
+	b.arches = append(b.arches, arches...)
+	b.perm = perm
+	return nil
+}
+
+// Remove removes the architectures
+func (b *FatBuilder) Remove(arches ...string) error {
+	if err := b.contains(arches...); err != nil {
+		return err
+	}
+	b.arches = remove(b.arches, arches...)
+	for _, a := range arches {
+		delete(b.hidden, a)
+	}
+	return nil
+}
+
+// Replace replaces the existing architectures with the inputs
+func (b *FatBuilder) Replace(inputs ...*ReplaceInput) error {
+	arches, err := b.l.openArches(inputs, MergeError)
+	if err != nil {
+		return err
+	}
+	b.opened = append(b.opened, arches...)
+
+	if err := b.contains(cpuStrings(arches)...); err != nil {
+		return err
+	}
+	b.arches = replace(b.arches, arches)
+	return nil
+}
+
+// Extract keeps only the architectures
+func (b *FatBuilder) Extract(arches ...string) error {
+	if err := b.contains(arches...); err != nil {
+		return err
+	}
+	b.arches = extract(b.arches, arches...)
+	for a := range b.hidden {
+		if len(extract(b.arches, a)) == 0 {
+			delete(b.hidden, a)
+		}
+	}
+	return nil
+}
+
+// SetAlign updates alignments of the architectures
+func (b *FatBuilder) SetAlign(aligns ...*SegAlignInput) error {
+	return updateAlignBit(b.arches, aligns)
+}
+
+// SetHidden hides the architecture in the fat header or makes it visible
+func (b *FatBuilder) SetHidden(arch string, hidden bool) error {
+	if err := b.contains(arch); err != nil {
+		return err
+	}
+	if hidden {
+		b.hidden[arch] = struct{}{}
+	} else {
+		delete(b.hidden, arch)
+	}
+	return nil
+}
+
+// Validate returns an error if the current architectures can't be written as a fat file
+func (b *FatBuilder) Validate() error {
+	_, err := b.layout()
+	return err
+}
+
+// Commit writes the current architectures to the output of the options
+func (b *FatBuilder) Commit() error {
+	return b.CommitContext(context.Background())
+}
+
+// CommitContext is Commit which stops writing the output when the context is done
+func (b *FatBuilder) CommitContext(ctx context.Context) error {
+	if _, err := b.layout(); err != nil {
+		return err
+	}
+	return b.l.createFatBinary(ctx, b.arches, b.perm)
+}
+
+// Close closes all inputs
+func (b *FatBuilder) Close() error {
+	close(b.opened...)
+	close(b.fats...)
+	return nil
+}
+
+func (b *FatBuilder) layout() (*lmacho.FatLayout[Arch], error) {
+	if len(b.arches) == 0 {
+		return nil, errors.New("no inputs would result in an empty fat file")
+	}
+	b.l.hidden = util.Filter(cpuStrings(b.arches), func(v string) bool {
+		_, ok := b.hidden[v]
+		return ok
+	})
+	b.l.hideArm64 = false
+	return lmacho.NewFatLayout(b.arches, b.l.fat64, false,
+		lmacho.WithHidden(b.l.hidden...), lmacho.WithOrder(b.l.order...))
+}
+
+func (b *FatBuilder) contains(arches ...string) error {
+	dup := util.Duplicates(arches, func(v string) string { return v })
+	if dup != nil {
+		return fmt.Errorf("%s specified multiple times", *dup)
+	}
+	if found := extract(b.arches, arches...); len(found) != len(arches) {
+		return fmt.Errorf("%s specified but the fat file does not contain that architecture", diff(cpuStrings(b.arches), arches))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package lipo_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestFatBuilder(t *testing.T) {
+	t.Run("remove replace segalign", func(t *testing.T) {
+		segAligns := []*lipo.SegAlignInput{{Arch: "x86_64", AlignHex: "4000"}}
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"}, testSegAlignOpt(segAligns))
+		newArm64 := p.NewArchBin(t, "arm64")
+
+		got := filepath.Join(p.Dir, gotName(t))
+		b, err := lipo.LoadFatBuilder(p.FatBin, lipo.WithOutput(got))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.Close()
+
+		if err := b.Remove("arm64e"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Replace(&lipo.ReplaceInput{Arch: "arm64", Bin: newArm64}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetAlign(&lipo.SegAlignInput{Arch: "x86_64", AlignHex: "4000"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		tmp := filepath.Join(p.Dir, "tmp-"+wantName(t))
+		p.Remove(t, tmp, p.FatBin, []string{"arm64e"})
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Replace(t, want, tmp, [][2]string{{"arm64", newArm64}})
+		diffSha256(t, want, got)
+	})
+
+	t.Run("add extract hidden", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64", "arm64e"})
+		got := filepath.Join(p.Dir, gotName(t))
+		b := lipo.NewFatBuilder(lipo.WithOutput(got))
+		defer b.Close()
+
+		if err := b.Add(&lipo.ArchInput{Bin: p.FatBin}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Extract("arm64", "arm64e"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetHidden("arm64e", true); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		verifyArches(t, got, "arm64", "arm64e")
+		ff, err := lipo.OpenFatFile(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ff.Close()
+		if ff.NArch != 1 {
+			t.Errorf("want 1 visible arch got %d", ff.NArch)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		b, err := lipo.LoadFatBuilder(p.FatBin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.Close()
+
+		wantErr := func(err error, want string) {
+			t.Helper()
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("want %q got %v", want, err)
+			}
+		}
+		wantErr(b.Remove("arm64e"), "arm64e specified but the fat file does not contain that architecture")
+		wantErr(b.Add(&lipo.ArchInput{Bin: p.Bin(t, "arm64")}), "the inputs have the same architectures (arm64)")
+		if err := b.SetHidden("arm64", true); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetHidden("x86_64", true); err != nil {
+			t.Fatal(err)
+		}
+		wantErr(b.Validate(), "all architectures are hidden")
+		if err := b.Remove("arm64", "x86_64"); err != nil {
+			t.Fatal(err)
+		}
+		wantErr(b.Validate(), "no inputs would result in an empty fat file")
+	})
+}
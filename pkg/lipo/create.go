@@ -1,9 +1,11 @@
 package lipo
 
 import (
+	"context"
 	"debug/macho"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/konoui/lipo/pkg/lmacho"
@@ -11,38 +13,74 @@ import (
 )
 
 func (l *Lipo) Create() error {
+	return l.CreateContext(context.Background())
+}
+
+// CreateContext is Create which stops writing the output when the context is done
+func (l *Lipo) CreateContext(ctx context.Context) error {
 	l.arches = append(l.arches, util.Map(l.in, func(v string) *ArchInput { return &ArchInput{Bin: v} })...)
 	archInputs := l.arches
 	if len(archInputs) == 0 {
 		return errNoInput
 	}
 
-	arches, err := OpenArches(archInputs)
+	arches, err := l.openArches(archInputs, l.policy)
 	if err != nil {
 		return err
 	}
 	defer close(arches...)
 
-	if err := updateAlignBit(arches, l.segAligns); err != nil {
+	// apple lipo will use a last file permission
+	// https://github.com/apple-oss-distributions/cctools/blob/cctools-973.0.1/misc/lipo.c#L1124
+	perm, err := l.perm(arches[len(arches)-1].Name())
+	if err != nil {
 		return err
 	}
 
-	// apple lipo will use a last file permission
-	// https://github.com/apple-oss-distributions/cctools/blob/cctools-973.0.1/misc/lipo.c#L1124
-	perm, err := perm(arches[len(arches)-1].Name())
+	blanks, err := newBlankArches(l.blanks)
 	if err != nil {
 		return err
 	}
+	arches = append(arches, blanks...)
 
-	return createFatBinary(l.out, arches, perm, l.fat64, l.hideArm64)
+	dup := util.Duplicates(arches, func(a Arch) string {
+		return a.CPUString()
+	})
+	if dup != nil {
+		return fmt.Errorf("the inputs have the same architectures (%s)", *dup)
+	}
+
+	if err := updateAlignBit(arches, l.segAligns); err != nil {
+		return err
+	}
+
+	return l.createFatBinary(ctx, arches, perm)
 }
 
-func createFatBinary[T Arch](path string, arches []T, perm os.FileMode, fat64 bool, hideARM64 bool) error {
+func newBlankArches(cpuStrings []string) ([]Arch, error) {
+	arches := make([]Arch, 0, len(cpuStrings))
+	for _, v := range cpuStrings {
+		cpu, sub, ok := lmacho.ToCpu(v)
+		if !ok {
+			return nil, fmt.Errorf(unsupportedArchFmt, v)
+		}
+		blank := lmacho.NewBlankArch(cpu, sub)
+		arches = append(arches, &arch{
+			Object:       blank,
+			name:         "blank",
+			updatedAlign: blank.Align(),
+			Closer:       &nopCloser{},
+		})
+	}
+	return arches, nil
+}
+
+func (l *Lipo) createFatBinary(ctx context.Context, arches []Arch, perm os.FileMode) error {
 	if len(arches) == 0 {
 		return errors.New("no inputs would result in an empty fat file")
 	}
 
-	if hideARM64 {
+	if l.hideArm64 {
 		for _, obj := range arches {
 			if obj.Type() == macho.TypeObj {
 				return fmt.Errorf("hideARM64 specified but thin file %s is not of type MH_EXECUTE", obj.Name())
@@ -50,29 +88,21 @@ func createFatBinary[T Arch](path string, arches []T, perm os.FileMode, fat64 bo
 		}
 	}
 
-	out, err := createTemp(path)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	if err := lmacho.CreateFat(out, arches, fat64, hideARM64); err != nil {
-		return err
-	}
-
-	if err := out.Chmod(perm); err != nil {
-		return err
+	opts := []lmacho.FatOption{
+		lmacho.WithHidden(l.hidden...),
+		lmacho.WithOrder(l.order...),
+		lmacho.WithProgress(l.progress),
 	}
-
-	if err := out.Sync(); err != nil {
+	layout, err := lmacho.NewFatLayout(arches, l.fat64, l.hideArm64, opts...)
+	if err != nil {
 		return err
 	}
 
-	// close before rename
-	if err := out.Close(); err != nil {
-		return err
+	if l.dryRun != nil {
+		return printFatPlan(l.dryRun, l.out, layout)
 	}
 
-	// atomic operation
-	return os.Rename(out.Name(), path)
+	return l.writeOutput(perm,
+		func(w io.Writer) error { return layout.WriteContext(ctx, w) },
+		func(f *os.File) error { return verifyFat(f, layout) })
 }
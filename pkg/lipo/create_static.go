@@ -0,0 +1,193 @@
+package lipo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/konoui/lipo/pkg/ar"
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+// CreateStatic merges members of the same architecture of the thin or fat static libraries and objects of the inputs
+// into an archive with the regenerated symbol table like `libtool -static` and creates the fat file of the archives.
+// Members which have the same name and contents are added once and members which have the same name but different contents are renamed.
+func (l *Lipo) CreateStatic() error {
+	return l.CreateStaticContext(context.Background())
+}
+
+// CreateStaticContext is CreateStatic which stops writing the output when the context is done
+func (l *Lipo) CreateStaticContext(ctx context.Context) error {
+	if len(l.in) == 0 {
+		return errNoInput
+	}
+
+	g := newMemberGroups()
+	for _, in := range l.in {
+		f, err := l.open(in)
+		if err != nil {
+			return err
+		}
+		// members refer to opened files until the output is written
+		defer f.Close()
+
+		if err := g.addStaticInput(f, in); err != nil {
+			return err
+		}
+	}
+
+	// apple lipo will use a last file permission
+	perm, err := l.perm(l.in[len(l.in)-1])
+	if err != nil {
+		return err
+	}
+
+	// the archives are staged in the same directory as the temporary output
+	dir, err := os.MkdirTemp(l.tempDir(), "tmp-lipo-static")
+	if err != nil {
+		return fmt.Errorf("can't create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputs := make([]*ArchInput, 0, len(g.order))
+	for _, cpu := range g.order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		members, err := uniqueMembers(g.groups[cpu])
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dir, cpu+".a")
+		if err := writeArchive(p, members, perm); err != nil {
+			return err
+		}
+		inputs = append(inputs, &ArchInput{Bin: p})
+	}
+
+	arches, err := l.openArches(inputs, MergeError)
+	if err != nil {
+		return err
+	}
+	defer close(arches...)
+
+	return l.createFatBinary(ctx, arches, perm)
+}
+
+// addStaticInput adds members of the thin or fat static library or the object itself as a member
+func (g *memberGroups) addStaticInput(f file, p string) error {
+	typ, err := inspectFile(f, p)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// the object is added with the base name like libtool
+	objHeader := ar.Header{Name: filepath.Base(p), ModTime: info.ModTime().Truncate(time.Second), Mode: 0100000 | info.Mode().Perm()}
+
+	switch typ {
+	case inspectArchive:
+		files, err := ar.NewArchive(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		return g.addArchive(p, files)
+	case inspectThin:
+		sr := io.NewSectionReader(f, 0, info.Size())
+		m, err := lmacho.NewArch(sr)
+		if err != nil {
+			return err
+		}
+		g.add(m.CPUString(), &archiveMember{hdr: objHeader, sr: sr})
+		return nil
+	case inspectFat:
+		ff, err := lmacho.NewFatFile(f)
+		if err != nil {
+			return err
+		}
+		for _, fa := range ff.Arches {
+			sr := io.NewSectionReader(fa, 0, int64(fa.Size()))
+			files, err := ar.NewArchive(sr)
+			if errors.Is(err, ar.ErrInvalidFormat) {
+				g.add(fa.CPUString(), &archiveMember{hdr: objHeader, sr: sr})
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s (%s): %w", p, fa.CPUString(), err)
+			}
+			if err := g.addArchive(fmt.Sprintf("%s (%s)", p, fa.CPUString()), files); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("can't figure out the architecture type of: %s", p)
+	}
+}
+
+// uniqueMembers drops members which have the same name and contents as previous members
+// and renames members which have the same name but different contents to `<name>-<n><ext>` in the order of the inputs.
+func uniqueMembers(members []*archiveMember) ([]*archiveMember, error) {
+	names := map[string]struct{}{}
+	for _, m := range members {
+		names[m.hdr.Name] = struct{}{}
+	}
+
+	ret := make([]*archiveMember, 0, len(members))
+	assigned := map[string]*archiveMember{}
+	for _, m := range members {
+		prev, ok := assigned[m.hdr.Name]
+		if !ok {
+			assigned[m.hdr.Name] = m
+			ret = append(ret, m)
+			continue
+		}
+
+		same, err := sameContents(prev.sr, m.sr)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			continue
+		}
+
+		ext := filepath.Ext(m.hdr.Name)
+		stem := strings.TrimSuffix(m.hdr.Name, ext)
+		renamed := *m
+		for n := 1; ; n++ {
+			name := fmt.Sprintf("%s-%d%s", stem, n, ext)
+			_, reserved := names[name]
+			if _, ok := assigned[name]; !ok && !reserved {
+				renamed.hdr.Name = name
+				break
+			}
+		}
+		assigned[renamed.hdr.Name] = &renamed
+		ret = append(ret, &renamed)
+	}
+	return ret, nil
+}
+
+func sameContents(a, b *io.SectionReader) (bool, error) {
+	if a.Size() != b.Size() {
+		return false, nil
+	}
+	x, err := io.ReadAll(io.NewSectionReader(a, 0, a.Size()))
+	if err != nil {
+		return false, err
+	}
+	y, err := io.ReadAll(io.NewSectionReader(b, 0, b.Size()))
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(x, y), nil
+}
@@ -0,0 +1,122 @@
+package lipo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/ar"
+	"github.com/konoui/lipo/pkg/lipo"
+)
+
+func thinMembers(t *testing.T, fat, arch string) []string {
+	t.Helper()
+	thin := filepath.Join(t.TempDir(), arch+".a")
+	if err := lipo.New(lipo.WithInputs(fat), lipo.WithOutput(thin)).Thin(arch); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range readArchive(t, thin) {
+		if !f.IsSymbolTable() {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func TestLipo_CreateStatic(t *testing.T) {
+	t.Run("thin and fat inputs", func(t *testing.T) {
+		got := filepath.Join(t.TempDir(), "libfunc.a")
+		inputs := []string{
+			"../ar/testdata/arm64-func12.a",
+			// the fat archive of arm64-func1.o and amd64-func1.o
+			"../ar/testdata/fat-arm64-amd64-func1",
+			"../ar/testdata/amd64-func2.o",
+		}
+		if err := lipo.New(lipo.WithInputs(inputs...), lipo.WithOutput(got)).CreateStatic(); err != nil {
+			t.Fatal(err)
+		}
+
+		verifyArches(t, got, "arm64", "x86_64")
+		// the same member of the fat archive is added once
+		if want, got := []string{"arm64-func1.o", "arm64-func2.o"}, thinMembers(t, got, "arm64"); !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v got %v", want, got)
+		}
+		if want, got := []string{"amd64-func1.o", "amd64-func2.o"}, thinMembers(t, got, "x86_64"); !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("name collision", func(t *testing.T) {
+		dir := t.TempDir()
+		// arm64-func1.o of the library is actually arm64-func2.o
+		lib := filepath.Join(dir, "libcollision.a")
+		data, err := os.ReadFile("../ar/testdata/arm64-func2.o")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		w := ar.NewWriter(buf, ar.WithSymbolTable())
+		if err := w.Add(&ar.Header{Name: "arm64-func1.o", Size: int64(len(data)), Mode: 0100644}, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, lib, buf.Bytes())
+
+		got := filepath.Join(dir, "libfunc.a")
+		inputs := []string{"../ar/testdata/arm64-func12.a", lib}
+		if err := lipo.New(lipo.WithInputs(inputs...), lipo.WithOutput(got)).CreateStatic(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"arm64-func1.o", "arm64-func2.o", "arm64-func1-1.o"}
+		if got := thinMembers(t, got, "arm64"); !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	})
+
+	t.Run("staged next to the output", func(t *testing.T) {
+		dir := t.TempDir()
+		// the temporary directory is not used for the output path
+		t.Setenv("TMPDIR", filepath.Join(dir, "not-found"))
+		got := filepath.Join(dir, "libfunc.a")
+		inputs := []string{"../ar/testdata/arm64-func12.a", "../ar/testdata/amd64-func12.a"}
+		if err := lipo.New(lipo.WithInputs(inputs...), lipo.WithOutput(got)).CreateStatic(); err != nil {
+			t.Fatal(err)
+		}
+
+		verifyArches(t, got, "arm64", "x86_64")
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("temporary files are left: %v", entries)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		got := filepath.Join(dir, "libfunc.a")
+		inputs := []string{"../ar/testdata/arm64-func12.a", "../ar/testdata/amd64-func12.a"}
+		if err := lipo.New(lipo.WithInputs(inputs...), lipo.WithOutput(got)).CreateStaticContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("want %v got %v", context.Canceled, err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("files are left: %v", entries)
+		}
+	})
+
+	t.Run("not static library", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty")
+		writeFile(t, empty, []byte{})
+		err := lipo.New(lipo.WithInputs(empty), lipo.WithOutput(empty+".a")).CreateStatic()
+		if err == nil {
+			t.Error("want an error")
+		}
+	})
+}
@@ -1,11 +1,15 @@
 package lipo_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/konoui/lipo/pkg/ar"
 	"github.com/konoui/lipo/pkg/lipo"
 	"github.com/konoui/lipo/pkg/lmacho"
 	"github.com/konoui/lipo/pkg/testlipo"
@@ -120,6 +124,50 @@ func TestLipo_CreateWithArchives(t *testing.T) {
 	})
 }
 
+func TestLipo_CreateWithGNUArchives(t *testing.T) {
+	dir := t.TempDir()
+	gnu := filepath.Join(dir, "arm64-gnu.a")
+	f, err := os.Create(gnu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ar.NewWriter(f, ar.WithFormat(ar.FormatGNU), ar.WithSymbolTable())
+	for _, obj := range []string{"arm64-func1.o", "arm64-func2.o"} {
+		data, err := os.ReadFile(filepath.Join("../ar/testdata", obj))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// names longer than 15 bytes are stored in the `//` table
+		hdr := &ar.Header{Name: "long-name-" + obj, Size: int64(len(data)), Mode: 0100644}
+		if err := w.Add(hdr, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got := filepath.Join(dir, "fat-archive-got")
+	opts := []lipo.Option{
+		lipo.WithInputs(gnu, "../ar/testdata/amd64-func12.a"),
+		lipo.WithOutput(got),
+	}
+	if err := lipo.New(opts...).Create(); err != nil {
+		t.Fatal(err)
+	}
+	verifyArches(t, got, "arm64", "x86_64")
+
+	t.Run("thin archive", func(t *testing.T) {
+		thin := filepath.Join(dir, "thin.a")
+		writeFile(t, thin, []byte("!<thin>\n"))
+		_, err := lipo.New(lipo.WithInputs(thin)).Archs()
+		if !errors.Is(err, ar.ErrThinArchive) {
+			t.Errorf("want ErrThinArchive got %v", err)
+		}
+	})
+}
+
 func TestLipo_CreateFromFat(t *testing.T) {
 	t.Run("from fat", func(t *testing.T) {
 		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
@@ -155,6 +203,242 @@ func TestLipo_CreateWithArch(t *testing.T) {
 
 		verifyArches(t, got, "x86_64", "arm64", "arm64e")
 	})
+
+	t.Run("arch-inputs-from-fat", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		q := testlipo.Setup(t, bm, []string{"arm64e", "x86_64h"})
+		archInputs := []*lipo.ArchInput{
+			{Arch: "arm64", Bin: p.FatBin},
+			{Arch: "arm64e", Bin: q.FatBin},
+		}
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bin(t, "x86_64")), lipo.WithOutput(got), lipo.WithArch(archInputs...)}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatalf("failed to create fat bin %v", err)
+		}
+
+		verifyArches(t, got, "x86_64", "arm64", "arm64e")
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Create(t, want, p.Bin(t, "arm64"), q.Bin(t, "arm64e"), p.Bin(t, "x86_64"))
+		diffSha256(t, want, got)
+	})
+
+	t.Run("arch-inputs-from-fat-not-contain", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		archInputs := []*lipo.ArchInput{{Arch: "arm64e", Bin: p.FatBin}}
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bin(t, "x86_64")), lipo.WithOutput(got), lipo.WithArch(archInputs...)}
+		err := lipo.New(opts...).Create()
+		want := "fat input file (" + p.FatBin + ") does not contain the specified architecture (arm64e)"
+		if err == nil || err.Error() != want {
+			t.Errorf("want: %s, got: %v", want, err)
+		}
+	})
+}
+
+func TestLipo_CreateWithArchErrorsCloseFiles(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("/proc/self/fd is not available")
+	}
+	countFds := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+	got := filepath.Join(t.TempDir(), gotName(t))
+	tests := [][]*lipo.ArchInput{
+		// thin arch mismatch after the fat file is opened
+		{{Bin: p.FatBin}, {Arch: "arm64", Bin: p.Bin(t, "x86_64")}},
+		// archive arch mismatch
+		{{Bin: p.Bin(t, "x86_64")}, {Arch: "x86_64", Bin: "../ar/testdata/arm64-func12.a"}},
+		// duplicated arches
+		{{Bin: p.FatBin}, {Bin: p.Bin(t, "arm64")}},
+	}
+
+	before := countFds()
+	for _, inputs := range tests {
+		if err := lipo.New(lipo.WithArch(inputs...), lipo.WithOutput(got)).Create(); err == nil {
+			t.Errorf("want an error for %v", inputs)
+		}
+	}
+	if after := countFds(); before != after {
+		t.Errorf("files are not closed: %d opened files before, %d after", before, after)
+	}
+}
+
+func TestLipo_CreateWithHidden(t *testing.T) {
+	t.Run("hide-x86_64", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got), lipo.WithHidden("x86_64")}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatalf("failed to create fat bin %v", err)
+		}
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		lipo.New(lipo.WithInputs(got)).DetailedInfo(stdout, stderr)
+		for _, want := range []string{"nfat_arch 1 (+1 hidden)", "architecture x86_64 (hidden)"} {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("want: %s, got: %s", want, stdout.String())
+			}
+		}
+		if want := "warning: " + got + ": hidden architecture x86_64 is not arm64"; !strings.Contains(stderr.String(), want) {
+			t.Errorf("want: %s, got: %s", want, stderr.String())
+		}
+	})
+}
+
+func TestLipo_CreateWithOrder(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"})
+		got := filepath.Join(p.Dir, gotName(t))
+		order := []string{"arm64e", "x86_64", "arm64"}
+		opts := []lipo.Option{lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got), lipo.WithOrder(order...)}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatalf("failed to create fat bin %v", err)
+		}
+
+		gotArches, err := lipo.New(lipo.WithInputs(got)).Archs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(order, " ") != strings.Join(gotArches, " ") {
+			t.Errorf("want: %v, got: %v", order, gotArches)
+		}
+	})
+}
+
+func TestLipo_CreateWithArchBlank(t *testing.T) {
+	t.Run("arch-blank", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bin(t, "x86_64")), lipo.WithOutput(got), lipo.WithArchBlank("arm64")}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatalf("failed to create fat bin %v", err)
+		}
+
+		verifyArches(t, got, "x86_64", "arm64")
+
+		ff, err := lipo.OpenFatFile(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ff.Close()
+		for _, a := range ff.Arches {
+			if blank := a.CPUString() == "arm64"; blank != lmacho.IsBlank(a) {
+				t.Errorf("%s: want blank %v, got size %d", a.CPUString(), blank, a.Size())
+			}
+		}
+
+		stdout := &bytes.Buffer{}
+		lipo.New(lipo.WithInputs(got)).DetailedInfo(stdout, stdout)
+		if want := "size 0\n"; !strings.Contains(stdout.String(), want) {
+			t.Errorf("want: %s, got: %s", want, stdout.String())
+		}
+
+		err = lipo.New(lipo.WithInputs(got), lipo.WithOutput(got+"-thin")).Thin("arm64")
+		if want := "fat input file contains the specified architecture (arm64) as a blank entry, can't thin it"; err == nil || err.Error() != want {
+			t.Errorf("want: %s, got: %v", want, err)
+		}
+	})
+
+	t.Run("duplicate-arch", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got), lipo.WithArchBlank("arm64")}
+		err := lipo.New(opts...).Create()
+		if want := "the inputs have the same architectures (arm64)"; err == nil || err.Error() != want {
+			t.Errorf("want: %s, got: %v", want, err)
+		}
+	})
+}
+
+func TestLipo_CreateWithMergePolicy(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+	q := testlipo.Setup(t, bm, []string{"arm64", "arm64e"})
+	objArm64 := p.NewArchObj(t, "arm64")
+	r := filepath.Join(p.Dir, "fat-obj_arm64-arm64e")
+	opts := []lipo.Option{lipo.WithInputs(objArm64, q.Bin(t, "arm64e")), lipo.WithOutput(r)}
+	if err := lipo.New(opts...).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		inputs     []string
+		policy     lipo.MergePolicy
+		wantInputs []string
+		wantErrMsg string
+	}{
+		{
+			name:       "error",
+			inputs:     []string{p.FatBin, q.FatBin},
+			policy:     lipo.MergeError,
+			wantErrMsg: "the inputs have the same architectures (arm64)",
+		},
+		{
+			name:       "first",
+			inputs:     []string{p.FatBin, r},
+			policy:     lipo.MergeFirst,
+			wantInputs: []string{p.Bin(t, "x86_64"), p.Bin(t, "arm64"), q.Bin(t, "arm64e")},
+		},
+		{
+			name:       "last",
+			inputs:     []string{p.FatBin, r},
+			policy:     lipo.MergeLast,
+			wantInputs: []string{p.Bin(t, "x86_64"), objArm64, q.Bin(t, "arm64e")},
+		},
+		{
+			name:       "identical-only",
+			inputs:     []string{p.FatBin, q.FatBin},
+			policy:     lipo.MergeIdenticalOnly,
+			wantInputs: []string{p.Bin(t, "x86_64"), p.Bin(t, "arm64"), q.Bin(t, "arm64e")},
+		},
+		{
+			name:       "identical-only but not identical",
+			inputs:     []string{p.FatBin, r},
+			policy:     lipo.MergeIdenticalOnly,
+			wantErrMsg: "the inputs have the same architectures (arm64) but " + p.FatBin + " and " + r + " are not identical",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filepath.Join(p.Dir, gotName(t))
+			opts := []lipo.Option{lipo.WithInputs(tt.inputs...), lipo.WithOutput(got), lipo.WithMergePolicy(tt.policy)}
+			err := lipo.New(opts...).Create()
+			if tt.wantErrMsg != "" {
+				if err == nil || err.Error() != tt.wantErrMsg {
+					t.Errorf("want: %s, got: %v", tt.wantErrMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := filepath.Join(p.Dir, wantName(t))
+			p.Create(t, want, tt.wantInputs...)
+			testlipo.DiffSha256(t, want, got)
+		})
+	}
+}
+
+func TestLipo_CreateWithVerifyOutput(t *testing.T) {
+	t.Run("verify-output", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "armv7k"}, testlipo.WithHideArm64(true))
+		got := filepath.Join(p.Dir, gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got), lipo.WithHideArm64(), lipo.WithVerifyOutput()}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatalf("failed to create fat bin %v", err)
+		}
+
+		diffSha256(t, p.FatBin, got)
+	})
 }
 
 func TestLipo_CreateNonMachoFile(t *testing.T) {
@@ -235,3 +519,39 @@ func TestLipo_CreateError(t *testing.T) {
 		})
 	}
 }
+
+func TestLipo_CreateContext(t *testing.T) {
+	t.Run("progress", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		var last lmacho.Progress
+		l := lipo.New(lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got),
+			lipo.WithProgress(func(v lmacho.Progress) { last = v }))
+		if err := l.CreateContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if last.Total == 0 || last.TotalWritten != last.Total {
+			t.Errorf("unexpected last progress %+v", last)
+		}
+		diffSha256(t, p.FatBin, got)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		got := filepath.Join(dir, gotName(t))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		l := lipo.New(lipo.WithInputs(p.Bins(t)...), lipo.WithOutput(got))
+		if err := l.CreateContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("want %v got %v", context.Canceled, err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("want no output got %d files", len(entries))
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package lipo
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// destination returns a writer of the output or nil if the output is a path
+func (l *Lipo) destination() io.Writer {
+	if l.dst != nil {
+		return l.dst
+	}
+	if l.out == stdioPath {
+		return l.stdout
+	}
+	return nil
+}
+
+// commit moves the closed temporary output to the output path or copies the verified output to the destination
+func (l *Lipo) commit(tmp string) error {
+	dst := l.destination()
+	if dst == nil {
+		// atomic operation
+		return os.Rename(tmp, l.outputPath())
+	}
+	defer os.Remove(tmp)
+
+	f, err := os.Open(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(dst, f); err != nil {
+		return fmt.Errorf("error write to the destination: %w", err)
+	}
+	return nil
+}
+
+// writeOutput writes the output by `write` directly to the destination,
+// or to a temporary file which is renamed to the output path on success.
+// `verify` reads back the temporary file if the output is verified. The destination is staged in this case.
+func (l *Lipo) writeOutput(perm os.FileMode, write func(w io.Writer) error, verify func(f *os.File) error) (err error) {
+	if dst := l.destination(); dst != nil && !l.verifyOutput {
+		return write(dst)
+	}
+
+	out, err := l.createTemp()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		out.Close()
+		// do not leave the temporary file on failure
+		if err != nil {
+			os.Remove(out.Name())
+		}
+	}()
+
+	if err := write(out); err != nil {
+		return err
+	}
+
+	if err := out.Chmod(perm); err != nil {
+		return err
+	}
+
+	if err := out.Sync(); err != nil {
+		return err
+	}
+
+	if l.verifyOutput && verify != nil {
+		if err := verify(out); err != nil {
+			return err
+		}
+	}
+
+	// close before commit
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return l.commit(out.Name())
+}
@@ -32,11 +32,17 @@ func (l *Lipo) DetailedInfo(stdout, stderr io.Writer) {
 		return
 	}
 
+	in, err := l.expandZips(l.in)
+	if err != nil {
+		fmt.Fprintln(stderr, "fatal error: "+err.Error())
+		return
+	}
+
 	var out strings.Builder
 
 	thin := []string{}
-	for _, bin := range l.in {
-		v, isFat, err := detailedInfo(bin)
+	for _, bin := range in {
+		v, isFat, err := l.detailedInfo(bin, stderr)
 		if err != nil {
 			fmt.Fprintln(stderr, "fatal error: "+err.Error())
 			return
@@ -74,28 +80,32 @@ type tplFatBinary struct {
 	Arches    []*tplFatArch
 }
 
-func detailedInfo(bin string) (string, bool, error) {
+func (l *Lipo) detailedInfo(bin string, stderr io.Writer) (string, bool, error) {
 	var out strings.Builder
 
-	typ, err := inspect(bin)
+	typ, err := l.inspect(bin)
 	if err != nil {
 		return "", false, err
 	}
 
 	if typ != inspectFat {
-		v, _, err := info(bin)
+		v, _, err := l.info(bin)
 		if err != nil {
 			return "", false, err
 		}
 		return fmt.Sprintf("input file %s is not a fat file\n%s", bin, v), false, nil
 	}
 
-	ff, err := OpenFatFile(bin)
+	ff, err := l.openFatFile(bin)
 	if err != nil {
 		return "", false, err
 	}
 	defer ff.Close()
 
+	for _, w := range ff.Warnings {
+		fmt.Fprintf(stderr, "warning: %s: %s\n", bin, w)
+	}
+
 	rawArches := util.Map(ff.Arches, func(v Arch) *lmacho.FatArch {
 		return v.(*arch).Object.(*lmacho.FatArch)
 	})
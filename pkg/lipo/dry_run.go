@@ -0,0 +1,108 @@
+package lipo
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+const fatPlanTpl = `Fat header plan for: {{ .Output }}
+fat_magic {{ .FatMagic }}
+nfat_arch {{ .NFatArch }}
+{{ range $i, $v := .Arches -}}
+architecture {{ .Arch }}
+    source {{ .Source }}
+    cputype {{ .CpuType }}
+    cpusubtype {{ .SubCpuType }}
+    offset {{ .Offset }}
+    size {{ .Size }}
+    align 2^{{ .AlignBit }} ({{ .Align }})
+    padding {{ .Padding }}
+{{ end -}}
+`
+
+const thinPlanTpl = `Thin file plan for: {{ .Output }}
+architecture {{ .Arch }}
+    source {{ .Source }}
+    size {{ .Size }}
+`
+
+var (
+	fatPlan  = template.Must(template.New("fat_plan").Parse(fatPlanTpl))
+	thinPlan = template.Must(template.New("thin_plan").Parse(thinPlanTpl))
+)
+
+type tplPlanArch struct {
+	*tplFatArch
+	Source  string
+	Padding uint64
+}
+
+type tplFatPlan struct {
+	Output   string
+	FatMagic string
+	NFatArch string
+	Arches   []*tplPlanArch
+}
+
+type tplThinPlan struct {
+	Output string
+	Arch   string
+	Source string
+	Size   uint64
+}
+
+// printFatPlan prints the planned fat header instead of writing the fat file
+func printFatPlan(w io.Writer, out string, layout *lmacho.FatLayout[Arch]) error {
+	var visible, hidden int
+	arches := make([]*tplPlanArch, len(layout.Arches))
+	end := lmacho.FatHeaderSize() + lmacho.FatArchHeaderSize(layout.Magic)*uint64(len(layout.Arches))
+	for i, fa := range layout.Arches {
+		ta := tplArch(fa)
+		if fa.Hidden {
+			ta.Arch = fmt.Sprintf("%s (hidden)", ta.Arch)
+			hidden++
+		} else {
+			visible++
+		}
+		if lmacho.IsBlank(fa) {
+			ta.Arch = fmt.Sprintf("%s (blank)", ta.Arch)
+		}
+
+		padding := uint64(0)
+		if fa.Offset() > end {
+			padding = fa.Offset() - end
+		}
+		end = max(end, fa.Offset()+fa.Size())
+
+		arches[i] = &tplPlanArch{
+			tplFatArch: ta,
+			Source:     layout.Objects[i].Name(),
+			Padding:    padding,
+		}
+	}
+
+	nFatArch := fmt.Sprintf("%d", visible)
+	if hidden > 0 {
+		nFatArch = fmt.Sprintf("%d (+%d hidden)", visible, hidden)
+	}
+
+	return fatPlan.Execute(w, &tplFatPlan{
+		Output:   out,
+		FatMagic: fmt.Sprintf("0x%x", layout.Magic),
+		NFatArch: nFatArch,
+		Arches:   arches,
+	})
+}
+
+// printThinPlan prints the planned thin file instead of writing it
+func printThinPlan(w io.Writer, out string, arch Arch) error {
+	return thinPlan.Execute(w, &tplThinPlan{
+		Output: out,
+		Arch:   arch.CPUString(),
+		Source: arch.Name(),
+		Size:   arch.Size(),
+	})
+}
@@ -0,0 +1,109 @@
+package lipo_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_DryRun(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"})
+
+	tests := []struct {
+		name string
+		run  func(l *lipo.Lipo) error
+		opts []lipo.Option
+	}{
+		{
+			name: "create",
+			run:  func(l *lipo.Lipo) error { return l.Create() },
+			opts: []lipo.Option{lipo.WithInputs(p.Bins(t)...)},
+		},
+		{
+			name: "extract",
+			run:  func(l *lipo.Lipo) error { return l.Extract("arm64", "x86_64") },
+			opts: []lipo.Option{lipo.WithInputs(p.FatBin)},
+		},
+		{
+			name: "extract_family",
+			run:  func(l *lipo.Lipo) error { return l.ExtractFamily("arm64") },
+			opts: []lipo.Option{lipo.WithInputs(p.FatBin)},
+		},
+		{
+			name: "remove",
+			run:  func(l *lipo.Lipo) error { return l.Remove("arm64e") },
+			opts: []lipo.Option{lipo.WithInputs(p.FatBin)},
+		},
+		{
+			name: "replace",
+			run: func(l *lipo.Lipo) error {
+				return l.Replace([]*lipo.ReplaceInput{{Arch: "arm64", Bin: p.Bin(t, "arm64")}})
+			},
+			opts: []lipo.Option{lipo.WithInputs(p.FatBin)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filepath.Join(p.Dir, gotName(t))
+			os.Remove(got)
+
+			planned := &bytes.Buffer{}
+			opts := append(tt.opts, lipo.WithOutput(got), lipo.WithSegAlign(&lipo.SegAlignInput{Arch: "arm64", AlignHex: "1000"}))
+			if err := tt.run(lipo.New(append(opts, lipo.WithDryRun(planned))...)); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(got); !os.IsNotExist(err) {
+				t.Fatalf("dry run must not create the output: %v", err)
+			}
+
+			if err := tt.run(lipo.New(opts...)); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ff, err := lmacho.NewFatFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantLines := []string{"Fat header plan for: " + got, fmt.Sprintf("nfat_arch %d", len(ff.Arches))}
+			for _, a := range ff.Arches {
+				wantLines = append(wantLines,
+					fmt.Sprintf("architecture %s\n    source ", a.CPUString()),
+					fmt.Sprintf("    offset %d\n    size %d\n    align 2^%d", a.Offset(), a.Size(), a.Align()))
+			}
+			for _, want := range wantLines {
+				if !strings.Contains(planned.String(), want) {
+					t.Errorf("want: %s, got:\n%s", want, planned.String())
+				}
+			}
+		})
+	}
+
+	t.Run("validation error", func(t *testing.T) {
+		got := filepath.Join(p.Dir, gotName(t))
+		planned := &bytes.Buffer{}
+		l := lipo.New(lipo.WithInputs(p.FatBin), lipo.WithOutput(got), lipo.WithDryRun(planned))
+		err := l.Remove("armv7k")
+		want := fmt.Sprintf("armv7k specified but fat file: %s does not contain that architecture", p.FatBin)
+		if err == nil || err.Error() != want {
+			t.Errorf("want: %s, got: %v", want, err)
+		}
+		if planned.Len() != 0 {
+			t.Errorf("unexpected plan: %s", planned.String())
+		}
+	})
+}
@@ -1,21 +1,27 @@
 package lipo
 
 import (
+	"context"
 	"fmt"
 )
 
 func (l *Lipo) Extract(arches ...string) error {
+	return l.ExtractContext(context.Background(), arches...)
+}
+
+// ExtractContext is Extract which stops writing the output when the context is done
+func (l *Lipo) ExtractContext(ctx context.Context, arches ...string) error {
 	if err := validateOneInput(l.in); err != nil {
 		return err
 	}
 
 	fatBin := l.in[0]
-	perm, err := perm(fatBin)
+	perm, err := l.perm(fatBin)
 	if err != nil {
 		return err
 	}
 
-	ff, err := OpenFatFile(fatBin)
+	ff, err := l.openFatFile(fatBin)
 	if err != nil {
 		return err
 	}
@@ -31,5 +37,5 @@ func (l *Lipo) Extract(arches ...string) error {
 		return err
 	}
 
-	return createFatBinary(l.out, extracted, perm, l.fat64, l.hideArm64)
+	return l.createFatBinary(ctx, extracted, perm)
 }
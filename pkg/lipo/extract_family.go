@@ -1,21 +1,27 @@
 package lipo
 
 import (
+	"context"
 	"fmt"
 )
 
 func (l *Lipo) ExtractFamily(arches ...string) error {
+	return l.ExtractFamilyContext(context.Background(), arches...)
+}
+
+// ExtractFamilyContext is ExtractFamily which stops writing the output when the context is done
+func (l *Lipo) ExtractFamilyContext(ctx context.Context, arches ...string) error {
 	if err := validateOneInput(l.in); err != nil {
 		return err
 	}
 
 	fatBin := l.in[0]
-	perm, err := perm(fatBin)
+	perm, err := l.perm(fatBin)
 	if err != nil {
 		return err
 	}
 
-	ff, err := OpenFatFile(fatBin)
+	ff, err := l.openFatFile(fatBin)
 	if err != nil {
 		return err
 	}
@@ -27,12 +33,12 @@ func (l *Lipo) ExtractFamily(arches ...string) error {
 	}
 
 	if len(extracted) == 1 {
-		return l.thin(perm, extracted[0])
+		return l.thin(ctx, perm, extracted[0])
 	}
 
 	if err := updateAlignBit(ff.Arches, l.segAligns); err != nil {
 		return err
 	}
 
-	return createFatBinary(l.out, extracted, perm, l.fat64, l.hideArm64)
+	return l.createFatBinary(ctx, extracted, perm)
 }
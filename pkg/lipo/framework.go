@@ -0,0 +1,54 @@
+package lipo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const frameworkExt = ".framework"
+
+// frameworkBinary returns the binary of the framework bundle or the path as it is if the path is not a framework bundle.
+// Both shallow bundles `Foo.framework/Foo` and versioned bundles `Foo.framework/Versions/Current/Foo` are supported.
+func frameworkBinary(p string) string {
+	if filepath.Ext(p) != frameworkExt {
+		return p
+	}
+	info, err := os.Stat(p)
+	if err != nil || !info.IsDir() {
+		return p
+	}
+
+	name := strings.TrimSuffix(filepath.Base(p), frameworkExt)
+	bin := filepath.Join(p, name)
+	if _, err := os.Stat(bin); err == nil {
+		return bin
+	}
+	// the top level symbolic link may be missing in versioned bundles
+	versioned := filepath.Join(p, "Versions", "Current", name)
+	if _, err := os.Stat(versioned); err == nil {
+		return versioned
+	}
+	return bin
+}
+
+// inFramework returns true if the path is in a framework bundle
+func inFramework(p string) bool {
+	return slices.ContainsFunc(strings.Split(filepath.ToSlash(p), "/"), func(e string) bool {
+		return filepath.Ext(e) == frameworkExt
+	})
+}
+
+// outputPath returns the path to write the output.
+// The output in a framework bundle is resolved to the real file to keep symbolic links of the bundle.
+func (l *Lipo) outputPath() string {
+	out := frameworkBinary(l.out)
+	if !inFramework(out) {
+		return out
+	}
+	if real, err := filepath.EvalSymlinks(out); err == nil {
+		return real
+	}
+	return out
+}
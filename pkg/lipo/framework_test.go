@@ -0,0 +1,81 @@
+package lipo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+// versionedFramework creates `Foo.framework` whose binary is `Versions/A/Foo`
+func versionedFramework(t *testing.T, dir, bin string) string {
+	t.Helper()
+	fw := filepath.Join(dir, "Foo.framework")
+	if err := os.MkdirAll(filepath.Join(fw, "Versions", "A"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	copyFile(t, bin, filepath.Join(fw, "Versions", "A", "Foo"))
+	if err := os.Symlink("A", filepath.Join(fw, "Versions", "Current")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("Versions/Current/Foo", filepath.Join(fw, "Foo")); err != nil {
+		t.Fatal(err)
+	}
+	return fw
+}
+
+func TestLipo_Framework(t *testing.T) {
+	t.Run("info-shallow", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		fw := filepath.Join(t.TempDir(), "Foo.framework")
+		if err := os.MkdirAll(fw, 0755); err != nil {
+			t.Fatal(err)
+		}
+		copyFile(t, p.FatBin, filepath.Join(fw, "Foo"))
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		lipo.New(lipo.WithInputs(fw)).Info(stdout, stderr)
+		want := strings.ReplaceAll(p.Info(t, p.FatBin), p.FatBin, fw)
+		if got := stdout.String(); want != got {
+			t.Errorf("\nwant:\n%s\ngot:\n%s\n%s", want, got, stderr.String())
+		}
+	})
+
+	t.Run("thin-in-place", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		fw := versionedFramework(t, t.TempDir(), p.FatBin)
+
+		if err := lipo.New(lipo.WithInputs(fw), lipo.WithOutput(fw)).Thin("arm64"); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := os.Readlink(filepath.Join(fw, "Foo")); err != nil || v != "Versions/Current/Foo" {
+			t.Errorf("symbolic link is not kept: %s %v", v, err)
+		}
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "arm64")
+		diffSha256(t, want, filepath.Join(fw, "Versions", "A", "Foo"))
+	})
+
+	t.Run("replace-top-level-link", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		fw := versionedFramework(t, t.TempDir(), p.FatBin)
+		out := filepath.Join(fw, "Foo")
+
+		l := lipo.New(lipo.WithInputs(fw), lipo.WithOutput(out))
+		if err := l.Replace([]*lipo.ReplaceInput{{Arch: "x86_64", Bin: p.NewArchBin(t, "x86_64")}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := os.Readlink(out); err != nil || v != "Versions/Current/Foo" {
+			t.Errorf("symbolic link is not kept: %s %v", v, err)
+		}
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Replace(t, want, p.FatBin, [][2]string{{"x86_64", p.NewArchBin(t, "x86_64")}})
+		diffSha256(t, want, filepath.Join(fw, "Versions", "A", "Foo"))
+	})
+}
@@ -6,24 +6,36 @@ import (
 	"strings"
 )
 
+const (
+	fatInfoFmt    = "Architectures in the fat file: %s are: %s"
+	nonFatInfoFmt = "Non-fat file: %s is architecture: %s"
+)
+
 func (l *Lipo) Info(stdout, stderr io.Writer) {
 	if len(l.in) == 0 {
 		fmt.Fprintln(stderr, "fatal error: "+errNoInput.Error())
 		return
 	}
 
-	fat := make([]string, 0, len(l.in))
-	thin := make([]string, 0, len(l.in))
-	for _, bin := range l.in {
-		v, typ, err := info(bin)
-		if err != nil {
-			fmt.Fprintln(stderr, err.Error())
+	in, err := l.expandZips(l.in)
+	if err != nil {
+		fmt.Fprintln(stderr, "fatal error: "+err.Error())
+		return
+	}
+
+	results := l.infoAll(in)
+	fat := make([]string, 0, len(in))
+	thin := make([]string, 0, len(in))
+	for _, r := range results {
+		// report the first error in the order of the inputs
+		if r.err != nil {
+			fmt.Fprintln(stderr, r.err.Error())
 			return
 		}
-		if typ == inspectFat {
-			fat = append(fat, v)
+		if r.typ == inspectFat {
+			fat = append(fat, r.v)
 		} else {
-			thin = append(thin, v)
+			thin = append(thin, r.v)
 		}
 	}
 
@@ -31,8 +43,24 @@ func (l *Lipo) Info(stdout, stderr io.Writer) {
 	fmt.Fprintln(stdout, out)
 }
 
-func info(bin string) (string, inspectType, error) {
-	arches, typ, err := archs(bin)
+type infoResult struct {
+	v   string
+	typ inspectType
+	err error
+}
+
+// infoAll inspects the inputs by the bounded workers and returns results in the order of the inputs
+func (l *Lipo) infoAll(in []string) []*infoResult {
+	results := make([]*infoResult, len(in))
+	parallel(l.concurrency, len(in), func(i int) {
+		v, typ, err := l.info(in[i])
+		results[i] = &infoResult{v: v, typ: typ, err: err}
+	})
+	return results
+}
+
+func (l *Lipo) info(bin string) (string, inspectType, error) {
+	arches, typ, err := l.archs(bin)
 	if err != nil {
 		return "", typ, err
 	}
@@ -42,9 +70,9 @@ func info(bin string) (string, inspectType, error) {
 	case inspectThin:
 		fallthrough
 	case inspectArchive:
-		return fmt.Sprintf("Non-fat file: %s is architecture: %s", bin, v), typ, nil
+		return fmt.Sprintf(nonFatInfoFmt, bin, v), typ, nil
 	case inspectFat:
-		return fmt.Sprintf("Architectures in the fat file: %s are: %s", bin, v), typ, nil
+		return fmt.Sprintf(fatInfoFmt, bin, v), typ, nil
 	default:
 		return "", inspectUnknown, fmt.Errorf("unexpected type: %d", typ)
 	}
@@ -26,6 +26,40 @@ func TestLipo_Info(t *testing.T) {
 		}
 	})
 
+	t.Run("concurrent-inputs", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "arm64e", "x86_64"})
+		ins := []string{}
+		for range 20 {
+			ins = append(ins, p.Bin(t, "arm64"), p.FatBin, p.Bin(t, "x86_64"), "../ar/testdata/arm64-func12.a")
+		}
+		l := lipo.New(lipo.WithInputs(ins...), lipo.WithConcurrency(4))
+
+		stdout := &bytes.Buffer{}
+		l.Info(stdout, stdout)
+		got := stdout.String()
+
+		want := p.Info(t, ins...)
+		if want != got {
+			t.Errorf("\nwant:\n%s\ngot:\n%s", want, got)
+		}
+	})
+
+	t.Run("concurrent-inputs-error", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		ins := []string{p.FatBin, "../ar/testdata/arm64-amd64-func12.a", p.Bin(t, "arm64"), "not-found"}
+		l := lipo.New(lipo.WithInputs(ins...), lipo.WithConcurrency(4))
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		l.Info(stdout, stderr)
+		if stdout.Len() != 0 {
+			t.Errorf("unexpected output: %s", stdout.String())
+		}
+		// the error of the first invalid input is reported
+		if got := stderr.String(); !strings.Contains(got, "arm64-amd64-func12.a") {
+			t.Errorf("unexpected error: %s", got)
+		}
+	})
+
 	t.Run("fat-file-of-single-arch", func(t *testing.T) {
 		p := testlipo.Setup(t, bm, []string{"arm64"})
 		ins := []string{p.FatBin}
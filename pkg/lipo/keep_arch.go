@@ -0,0 +1,165 @@
+package lipo
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/util"
+)
+
+// KeepResult presents a fat file thinned by KeepArches
+type KeepResult struct {
+	Path string
+	// Removed is the architectures removed from the file
+	Removed []string
+	Before  int64
+	After   int64
+}
+
+// Saved returns bytes saved by removing architectures
+func (r *KeepResult) Saved() int64 {
+	return r.Before - r.After
+}
+
+// KeepArches walks the directory and keeps only the architectures in every fat file in place.
+// A fat file is thinned if one architecture remains, otherwise it is extracted like Extract.
+// Fat files without any of the architectures, thin files, symbolic links and other files are not changed.
+// The results contain only the changed files in lexical order. Zip files are not changed in place, use KeepArchesZip instead.
+func (l *Lipo) KeepArches(dir string, arches ...string) ([]*KeepResult, error) {
+	return l.KeepArchesContext(context.Background(), dir, arches...)
+}
+
+// KeepArchesContext is KeepArches which stops writing files when the context is done
+func (l *Lipo) KeepArchesContext(ctx context.Context, dir string, arches ...string) ([]*KeepResult, error) {
+	if err := validateKeepArches(arches); err != nil {
+		return nil, err
+	}
+	if isZip(dir) {
+		return nil, fmt.Errorf("the output must be specified for zip file: %s", dir)
+	}
+
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*KeepResult, len(paths))
+	errs := make([]error, len(paths))
+	parallel(l.concurrency, len(paths), func(i int) {
+		results[i], errs[i] = l.keepArches(ctx, paths[i], arches)
+	})
+
+	changed := make([]*KeepResult, 0, len(results))
+	for i, r := range results {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("%s: %w", paths[i], errs[i])
+		}
+		if r != nil {
+			changed = append(changed, r)
+		}
+	}
+	return changed, nil
+}
+
+func validateKeepArches(arches []string) error {
+	if len(arches) == 0 {
+		return fmt.Errorf("no architectures specified")
+	}
+	for _, v := range arches {
+		if !lmacho.IsSupportedCpu(v) {
+			return fmt.Errorf(unsupportedArchFmt, v)
+		}
+	}
+	return nil
+}
+
+// keepArches returns nil if the file is not changed
+func (l *Lipo) keepArches(ctx context.Context, path string, arches []string) (*KeepResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the output replaces the input like -output of the same path
+	removed, err := l.keep(ctx, path, arches, func() (*Lipo, error) { return New(WithOutput(path)), nil })
+	if err != nil || removed == nil {
+		return nil, err
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeepResult{
+		Path:    path,
+		Removed: removed,
+		Before:  info.Size(),
+		After:   after.Size(),
+	}, nil
+}
+
+// keep writes the fat file with only the architectures to the output of the lipo returned by `open`.
+// It returns the removed architectures or nil without calling `open` if the input is not a fat file to be changed.
+func (l *Lipo) keep(ctx context.Context, name string, arches []string, open func() (*Lipo, error)) ([]string, error) {
+	typ, err := l.inspect(name)
+	if err != nil || typ != inspectFat {
+		return nil, nil
+	}
+
+	perm, err := l.perm(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ff, err := l.openFatFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer ff.Close()
+
+	kept := extract(ff.Arches, arches...)
+	if len(kept) == 0 || len(kept) == len(ff.Arches) {
+		return nil, nil
+	}
+
+	hidden := cpuStrings(util.Filter(kept, func(a Arch) bool {
+		return a.(*arch).Object.(*lmacho.FatArch).Hidden
+	}))
+	if len(hidden) == len(kept) {
+		// at least one visible architecture is required
+		hidden = nil
+	}
+
+	// the output is opened only if the input is changed
+	out, err := open()
+	if err != nil {
+		return nil, err
+	}
+	out.hidden = hidden
+	out.fat64 = ff.Magic == lmacho.MagicFat64
+	out.verifyOutput = l.verifyOutput
+	if len(kept) == 1 {
+		err = out.thin(ctx, perm, kept[0])
+	} else {
+		err = out.createFatBinary(ctx, kept, perm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cpuStrings(remove(ff.Arches, arches...)), nil
+}
@@ -0,0 +1,72 @@
+package lipo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_KeepArches(t *testing.T) {
+	p1 := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"})
+	p2 := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "Contents", "MacOS")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	extracted := filepath.Join(dir, "fat")
+	thinned := filepath.Join(sub, "fat")
+	thin := filepath.Join(sub, "thin")
+	link := filepath.Join(dir, "link")
+	copyFile(t, p1.FatBin, extracted)
+	copyFile(t, p2.FatBin, thinned)
+	copyFile(t, p1.Bin(t, "x86_64"), thin)
+	if err := os.Symlink("Contents/MacOS/fat", link); err != nil {
+		t.Fatal(err)
+	}
+
+	l := lipo.New()
+	got, err := l.KeepArches(dir, "arm64", "arm64e")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("want 2 changed files got %d", len(got))
+	}
+	if got[0].Path != thinned || got[1].Path != extracted {
+		t.Errorf("unexpected paths %s %s", got[0].Path, got[1].Path)
+	}
+	for _, r := range got {
+		if r.Saved() <= 0 || len(r.Removed) != 1 || r.Removed[0] != "x86_64" {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+
+	wantExtracted := filepath.Join(p1.Dir, wantName(t)+"-extract")
+	p1.Extract(t, wantExtracted, p1.FatBin, []string{"arm64", "arm64e"})
+	diffSha256(t, wantExtracted, extracted)
+
+	wantThinned := filepath.Join(p2.Dir, wantName(t)+"-thin")
+	p2.Thin(t, wantThinned, p2.FatBin, "arm64")
+	diffSha256(t, wantThinned, thinned)
+
+	// thin files and symbolic links are not changed
+	testlipo.DiffSha256(t, p1.Bin(t, "x86_64"), thin)
+	if v, err := os.Readlink(link); err != nil || v != "Contents/MacOS/fat" {
+		t.Errorf("symbolic link is changed: %s %v", v, err)
+	}
+
+	// the second run changes nothing
+	got, err = l.KeepArches(dir, "arm64", "arm64e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("want no changed files got %d", len(got))
+	}
+}
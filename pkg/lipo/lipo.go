@@ -3,10 +3,14 @@ package lipo
 import (
 	"errors"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
+	"github.com/konoui/lipo/pkg/lmacho"
 	"github.com/konoui/lipo/pkg/util"
 )
 
@@ -24,8 +28,28 @@ type Lipo struct {
 	out       string
 	segAligns []*SegAlignInput
 	arches    []*ArchInput
+	blanks    []string
 	hideArm64 bool
+	hidden    []string
+	order     []string
 	fat64     bool
+	policy    MergePolicy
+	// verifyOutput reads back an output before renaming it to the destination
+	verifyOutput bool
+	// dryRun is a destination of planned layouts instead of writing outputs
+	dryRun io.Writer
+	stdin  *stdin
+	stdout io.Writer
+	// mu guards sources and closers which are added while inputs are opened concurrently
+	mu      sync.Mutex
+	sources map[string]*Source
+	// closers are closed on Close
+	closers []io.Closer
+	// dst is a destination of the output instead of the output path
+	dst      io.Writer
+	progress lmacho.ProgressFunc
+	// concurrency is the number of workers to inspect inputs
+	concurrency int
 }
 
 type SegAlignInput struct {
@@ -36,6 +60,15 @@ type SegAlignInput struct {
 type ArchInput struct {
 	Arch string
 	Bin  string
+	// Source is used instead of Bin if specified
+	Source *Source
+}
+
+func (a *ArchInput) name() string {
+	if a.Source != nil {
+		return a.Source.Name
+	}
+	return a.Bin
 }
 
 type ReplaceInput = ArchInput
@@ -48,6 +81,15 @@ func WithInputs(in ...string) Option {
 	}
 }
 
+// WithSources specifies sources as inputs.
+// The name of a source is used as the input path by operations and takes precedence over a file of the same path.
+func WithSources(srcs ...*Source) Option {
+	return func(l *Lipo) {
+		l.addSources(srcs...)
+		l.in = util.Map(srcs, func(s *Source) string { return s.Name })
+	}
+}
+
 func WithOutput(out string) Option {
 	return func(l *Lipo) {
 		l.out = out
@@ -66,12 +108,101 @@ func WithArch(arches ...*ArchInput) Option {
 	}
 }
 
+// WithArchBlank reserves empty entries for the specified architectures
+func WithArchBlank(arches ...string) Option {
+	return func(l *Lipo) {
+		l.blanks = arches
+	}
+}
+
 func WithHideArm64() Option {
 	return func(l *Lipo) {
 		l.hideArm64 = true
 	}
 }
 
+// WithHidden hides the specified architectures in the fat header of the output
+func WithHidden(arches ...string) Option {
+	return func(l *Lipo) {
+		l.hidden = arches
+	}
+}
+
+// WithOrder arranges architectures of the output in the specified order.
+// All architectures of the output must be specified exactly once.
+func WithOrder(arches ...string) Option {
+	return func(l *Lipo) {
+		l.order = arches
+	}
+}
+
+// WithMergePolicy specifies how to handle the same architectures in inputs of Create
+func WithMergePolicy(policy MergePolicy) Option {
+	return func(l *Lipo) {
+		l.policy = policy
+	}
+}
+
+// WithVerifyOutput verifies a written output against its sources before renaming it to the output path
+func WithVerifyOutput() Option {
+	return func(l *Lipo) {
+		l.verifyOutput = true
+	}
+}
+
+// WithDryRun validates inputs and prints the planned layout to `w` without writing the output
+func WithDryRun(w io.Writer) Option {
+	return func(l *Lipo) {
+		l.dryRun = w
+	}
+}
+
+// WithDestination writes the output to `w` instead of the output path.
+// The output is written to `w` directly and may be partially written on failure.
+// It is staged in a temporary file and copied to `w` only on success if the output is verified.
+func WithDestination(w io.Writer) Option {
+	return func(l *Lipo) {
+		l.dst = w
+	}
+}
+
+// WithDestinationAt writes the output to `w` from offset zero instead of the output path.
+// The output is written to `w` directly and may be partially written on failure.
+// It is staged in a temporary file and copied to `w` only on success if the output is verified.
+func WithDestinationAt(w io.WriterAt) Option {
+	return func(l *Lipo) {
+		l.dst = io.NewOffsetWriter(w, 0)
+	}
+}
+
+// WithProgress reports bytes written of each architecture of the output to `fn`
+func WithProgress(fn lmacho.ProgressFunc) Option {
+	return func(l *Lipo) {
+		l.progress = fn
+	}
+}
+
+// WithConcurrency specifies the number of workers to inspect inputs. The default is GOMAXPROCS
+func WithConcurrency(n int) Option {
+	return func(l *Lipo) {
+		l.concurrency = max(n, 1)
+	}
+}
+
+// WithStdin specifies a reader of the `-` input. The default is os.Stdin
+func WithStdin(r io.Reader) Option {
+	return func(l *Lipo) {
+		l.stdin = &stdin{r: r}
+	}
+}
+
+// WithStdout specifies a writer of the `-` output. The default is os.Stdout
+func WithStdout(w io.Writer) Option {
+	return func(l *Lipo) {
+		l.stdout = w
+	}
+}
+
 func WithFat64() Option {
 	return func(l *Lipo) {
 		l.fat64 = true
@@ -79,7 +210,11 @@ func WithFat64() Option {
 }
 
 func New(opts ...Option) *Lipo {
-	l := &Lipo{}
+	l := &Lipo{
+		stdin:       &stdin{r: os.Stdin},
+		stdout:      os.Stdout,
+		concurrency: runtime.GOMAXPROCS(0),
+	}
 	for _, opt := range opts {
 		if opt == nil {
 			continue
@@ -89,9 +224,18 @@ func New(opts ...Option) *Lipo {
 	return l
 }
 
-// createTemp creates a temporary file from file path
-func createTemp(path string) (*os.File, error) {
-	f, err := os.CreateTemp(filepath.Dir(path), "tmp-lipo-out")
+// tempDir returns the directory to stage files of the output.
+// It is the directory of the output path or the temporary directory if the output is not a path.
+func (l *Lipo) tempDir() string {
+	if l.destination() != nil {
+		return os.TempDir()
+	}
+	return filepath.Dir(l.outputPath())
+}
+
+// createTemp creates a temporary file for the output.
+func (l *Lipo) createTemp() (*os.File, error) {
+	f, err := os.CreateTemp(l.tempDir(), "tmp-lipo-out")
 	if err != nil {
 		return nil, fmt.Errorf("can't create temporary output file: %w", err)
 	}
@@ -108,13 +252,22 @@ func validateOneInput(inputs []string) error {
 	return nil
 }
 
-func perm(f string) (fs.FileMode, error) {
-	info, err := os.Stat(f)
-	if err != nil {
-		return 0, err
+// parallel calls fn for each index of `n` by the bounded workers
+func parallel(workers, n int, fn func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Go(func() {
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		})
 	}
-	perm := info.Mode().Perm() & 07777
-	return perm, nil
+	wg.Wait()
 }
 
 // diff return values `a` does not have
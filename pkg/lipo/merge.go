@@ -0,0 +1,118 @@
+package lipo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/konoui/lipo/pkg/util"
+)
+
+// MergePolicy presents how to handle the same architectures in inputs
+type MergePolicy string
+
+const (
+	// MergeError returns an error if the inputs have the same architectures
+	MergeError MergePolicy = "error"
+	// MergeFirst uses the architecture of the first input
+	MergeFirst MergePolicy = "first"
+	// MergeLast uses the architecture of the last input
+	MergeLast MergePolicy = "last"
+	// MergeIdenticalOnly allows the same architectures only if their contents are identical
+	MergeIdenticalOnly MergePolicy = "identical-only"
+)
+
+func MergePolicies() []MergePolicy {
+	return []MergePolicy{MergeError, MergeFirst, MergeLast, MergeIdenticalOnly}
+}
+
+func ParseMergePolicy(v string) (MergePolicy, error) {
+	for _, p := range MergePolicies() {
+		if string(p) == v {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported merge policy: %s", v)
+}
+
+// mergeArches resolves the same architectures in `arches` by the `policy`.
+// The dropped arches are closed.
+func mergeArches(arches []Arch, policy MergePolicy) ([]Arch, error) {
+	switch policy {
+	case "", MergeError:
+		dup := util.Duplicates(arches, func(a Arch) string {
+			return a.CPUString()
+		})
+		if dup != nil {
+			return nil, fmt.Errorf("the inputs have the same architectures (%s)", *dup)
+		}
+		return arches, nil
+	case MergeFirst, MergeIdenticalOnly:
+		seen := map[string]Arch{}
+		merged := make([]Arch, 0, len(arches))
+		for _, a := range arches {
+			first, ok := seen[a.CPUString()]
+			if !ok {
+				seen[a.CPUString()] = a
+				merged = append(merged, a)
+				continue
+			}
+
+			if policy == MergeIdenticalOnly {
+				same, err := identical(first, a)
+				if err != nil {
+					return nil, err
+				}
+				if !same {
+					return nil, fmt.Errorf("the inputs have the same architectures (%s) but %s and %s are not identical", a.CPUString(), first.Name(), a.Name())
+				}
+			}
+			a.Close()
+		}
+		return merged, nil
+	case MergeLast:
+		last := map[string]int{}
+		for i, a := range arches {
+			last[a.CPUString()] = i
+		}
+		merged := make([]Arch, 0, len(arches))
+		for i, a := range arches {
+			if last[a.CPUString()] != i {
+				a.Close()
+				continue
+			}
+			merged = append(merged, a)
+		}
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("unsupported merge policy: %s", policy)
+	}
+}
+
+// identical compares contents of `a` and `b` by streaming hashes
+func identical(a, b Arch) (bool, error) {
+	if a.Size() != b.Size() {
+		return false, nil
+	}
+
+	ha, err := sha256Sum(a, int64(a.Size()))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", a.Name(), err)
+	}
+
+	hb, err := sha256Sum(b, int64(b.Size()))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	return bytes.Equal(ha, hb), nil
+}
+
+func sha256Sum(r io.ReaderAt, size int64) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
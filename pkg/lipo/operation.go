@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"math"
-	"os"
 	"strconv"
 	"strings"
 
@@ -122,22 +122,27 @@ const (
 	inspectUnknown
 )
 
-func inspect(p string) (inspectType, error) {
-	f, err := os.Open(p)
+func (l *Lipo) inspect(p string) (inspectType, error) {
+	f, err := l.open(p)
 	if err != nil {
 		return inspectUnknown, err
 	}
 	defer f.Close()
 
+	return inspectFile(f, p)
+}
+
+// inspectFile inspects the opened file without changing its offset
+func inspectFile(f io.ReaderAt, p string) (inspectType, error) {
 	baseErr := fmt.Errorf("can't figure out the architecture type of: %s", p)
 	inspectedErrs := []error{}
 
 	buf := make([]byte, 40)
-	if _, err := f.Read(buf); err != nil {
+	if n, err := f.ReadAt(buf, 0); n == 0 && err != nil {
 		return inspectUnknown, errors.Join(baseErr, errors.New("cannot read first 40 bytes"))
 	}
 
-	_, err = lmacho.NewFatIter(bytes.NewReader(buf))
+	_, err := lmacho.NewFatIter(bytes.NewReader(buf))
 	if err == nil {
 		return inspectFat, nil
 	}
@@ -151,6 +156,9 @@ func inspect(p string) (inspectType, error) {
 	if err == nil {
 		return inspectArchive, nil
 	}
+	if errors.Is(err, ar.ErrThinArchive) {
+		return inspectUnknown, fmt.Errorf("%s: %w", p, err)
+	}
 
 	inspectedErrs = append(inspectedErrs, err)
 	return inspectUnknown, errors.Join(baseErr, errors.Join(inspectedErrs...))
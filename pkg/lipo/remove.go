@@ -1,21 +1,27 @@
 package lipo
 
 import (
+	"context"
 	"fmt"
 )
 
 func (l *Lipo) Remove(arches ...string) (err error) {
+	return l.RemoveContext(context.Background(), arches...)
+}
+
+// RemoveContext is Remove which stops writing the output when the context is done
+func (l *Lipo) RemoveContext(ctx context.Context, arches ...string) (err error) {
 	if err := validateOneInput(l.in); err != nil {
 		return err
 	}
 
 	fatBin := l.in[0]
-	perm, err := perm(fatBin)
+	perm, err := l.perm(fatBin)
 	if err != nil {
 		return err
 	}
 
-	ff, err := OpenFatFile(fatBin)
+	ff, err := l.openFatFile(fatBin)
 	if err != nil {
 		return err
 	}
@@ -31,6 +37,6 @@ func (l *Lipo) Remove(arches ...string) (err error) {
 		return err
 	}
 
-	return createFatBinary(l.out, removed, perm, l.fat64, l.hideArm64)
+	return l.createFatBinary(ctx, removed, perm)
 
 }
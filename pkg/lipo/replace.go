@@ -1,27 +1,33 @@
 package lipo
 
 import (
+	"context"
 	"fmt"
 )
 
 func (l *Lipo) Replace(inputs []*ReplaceInput) error {
+	return l.ReplaceContext(context.Background(), inputs)
+}
+
+// ReplaceContext is Replace which stops writing the output when the context is done
+func (l *Lipo) ReplaceContext(ctx context.Context, inputs []*ReplaceInput) error {
 	if err := validateOneInput(l.in); err != nil {
 		return err
 	}
 
 	fatBin := l.in[0]
-	perm, err := perm(fatBin)
+	perm, err := l.perm(fatBin)
 	if err != nil {
 		return err
 	}
 
-	ff, err := OpenFatFile(fatBin)
+	ff, err := l.openFatFile(fatBin)
 	if err != nil {
 		return err
 	}
 	defer ff.Close()
 
-	arches, err := OpenArches(inputs)
+	arches, err := l.openArches(inputs, MergeError)
 	if err != nil {
 		return err
 	}
@@ -38,5 +44,5 @@ func (l *Lipo) Replace(inputs []*ReplaceInput) error {
 		return err
 	}
 
-	return createFatBinary(l.out, newArches, perm, l.fat64, l.hideArm64)
+	return l.createFatBinary(ctx, newArches, perm)
 }
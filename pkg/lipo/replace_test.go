@@ -97,6 +97,42 @@ func TestLipo_Replace(t *testing.T) {
 	}
 }
 
+func TestLipo_ReplaceFromFat(t *testing.T) {
+	t.Run("replace-from-fat", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		q := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"})
+
+		got := filepath.Join(p.Dir, gotName(t))
+		ri := []*lipo.ReplaceInput{{Arch: "arm64", Bin: q.FatBin}}
+		if err := lipo.New(lipo.WithInputs(p.FatBin), lipo.WithOutput(got)).Replace(ri); err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Replace(t, want, p.FatBin, [][2]string{{"arm64", q.Bin(t, "arm64")}})
+		diffSha256(t, want, got)
+	})
+}
+
+func TestLipo_ReplaceArchBlank(t *testing.T) {
+	t.Run("fill-blank", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		blank := filepath.Join(p.Dir, "blank-"+gotName(t))
+		opts := []lipo.Option{lipo.WithInputs(p.Bin(t, "x86_64")), lipo.WithOutput(blank), lipo.WithArchBlank("arm64")}
+		if err := lipo.New(opts...).Create(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		ri := []*lipo.ReplaceInput{{Arch: "arm64", Bin: p.Bin(t, "arm64")}}
+		if err := lipo.New(lipo.WithInputs(blank), lipo.WithOutput(got)).Replace(ri); err != nil {
+			t.Fatal(err)
+		}
+
+		testlipo.DiffSha256(t, p.FatBin, got)
+	})
+}
+
 func wrapReplaceInputs(ri []*lipo.ReplaceInput) [][2]string {
 	ret := [][2]string{}
 	for _, i := range ri {
@@ -0,0 +1,146 @@
+package lipo
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konoui/lipo/pkg/ar"
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/util"
+)
+
+// ScanResult presents architectures of a Mach-O file, a fat file or an archive found by Scan
+type ScanResult struct {
+	Path    string
+	Fat     bool
+	Archive bool
+	Arches  []string
+	// Missing is the required architectures which the file does not contain
+	Missing []string
+	// Err is an error to read the file or architectures of the file which looks like a Mach-O file or an archive
+	Err error
+}
+
+// Info returns the same line as Info
+func (r *ScanResult) Info() string {
+	v := strings.Join(r.Arches, " ")
+	if r.Fat {
+		return fmt.Sprintf(fatInfoFmt, r.Path, v)
+	}
+	return fmt.Sprintf(nonFatInfoFmt, r.Path, v)
+}
+
+// Scan walks the directory and returns Mach-O files, fat files and archives in lexical order.
+// Symbolic links are not followed and files whose magic is unknown are skipped.
+// Files which can't be read or parsed are returned with Err.
+// Missing of each result is filled if `required` architectures are specified.
+func (l *Lipo) Scan(dir string, required ...string) ([]*ScanResult, error) {
+	for _, v := range required {
+		if !lmacho.IsSupportedCpu(v) {
+			return nil, fmt.Errorf(unsupportedArchFmt, v)
+		}
+	}
+
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ScanResult, len(paths))
+	parallel(l.concurrency, len(paths), func(i int) {
+		results[i] = scan(paths[i], required)
+	})
+
+	found := make([]*ScanResult, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+// scan returns nil if the magic of the file is neither of a Mach-O file nor of an archive.
+// Errors of the file which can't be read or looks like a Mach-O file or an archive are set to Err of the result.
+func scan(path string, required []string) *ScanResult {
+	r := &ScanResult{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer f.Close()
+
+	ok, err := hasObjectMagic(f)
+	if err != nil {
+		r.Err = fmt.Errorf("%s: %w", path, err)
+		return r
+	}
+	if !ok {
+		return nil
+	}
+
+	typ, err := inspectFile(f, path)
+	if err != nil {
+		// e.g. truncated headers or a GNU thin archive
+		r.Err = err
+		return r
+	}
+
+	r.Fat = typ == inspectFat
+	r.Archive = typ == inspectArchive
+	r.Arches, r.Err = archsFile(f, path, typ)
+	if r.Err != nil {
+		return r
+	}
+
+	m := util.ExistenceMap(r.Arches, func(v string) string { return v })
+	r.Missing = util.Filter(required, func(v string) bool {
+		_, ok := m[v]
+		return !ok
+	})
+	return r
+}
+
+// hasObjectMagic returns true if the file starts with the magic of a Mach-O file, a fat file or an archive including a GNU thin archive
+func hasObjectMagic(f io.ReaderAt) (bool, error) {
+	buf := make([]byte, len(ar.MagicHeader))
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	buf = buf[:n]
+
+	if bytes.Equal(buf, ar.MagicHeader) || bytes.Equal(buf, []byte("!<thin>\n")) {
+		return true, nil
+	}
+	if len(buf) < 4 {
+		return false, nil
+	}
+	switch binary.BigEndian.Uint32(buf) {
+	case lmacho.MagicFat, lmacho.MagicFat64:
+		return true, nil
+	}
+	switch binary.LittleEndian.Uint32(buf) {
+	case macho.Magic32, macho.Magic64:
+		return true, nil
+	}
+	return false, nil
+}
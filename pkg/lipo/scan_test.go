@@ -0,0 +1,103 @@
+package lipo_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_Scan(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+	dir := t.TempDir()
+	macos := filepath.Join(dir, "App.app", "Contents", "MacOS")
+	frameworks := filepath.Join(dir, "App.app", "Contents", "Frameworks")
+	for _, d := range []string{macos, frameworks} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile(t, p.FatBin, filepath.Join(macos, "App"))
+	copyFile(t, p.Bin(t, "arm64"), filepath.Join(frameworks, "helper"))
+	copyFile(t, "../ar/testdata/arm64-func12.a", filepath.Join(frameworks, "libfunc.a"))
+	copyFile(t, "../ar/testdata/arm64-amd64-func12.a", filepath.Join(frameworks, "libmixed.a"))
+	writeFile(t, filepath.Join(dir, "App.app", "Contents", "Info.plist"), []byte("<plist></plist>"))
+	// files which look like Mach-O files or archives but can't be read are reported
+	writeFile(t, filepath.Join(frameworks, "libthin.a"), []byte("!<thin>\n"))
+	writeFile(t, filepath.Join(frameworks, "truncated"), []byte{0xca, 0xfe, 0xba, 0xbe})
+	if err := os.Symlink(filepath.Join(macos, "App"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := lipo.New().Scan(dir, "x86_64", "arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*lipo.ScanResult{
+		{
+			Path:   filepath.Join(frameworks, "helper"),
+			Arches: []string{"arm64"},
+			// the required order is kept
+			Missing: []string{"x86_64"},
+		},
+		{
+			Path:    filepath.Join(frameworks, "libfunc.a"),
+			Archive: true,
+			Arches:  []string{"arm64"},
+			Missing: []string{"x86_64"},
+		},
+		{
+			Path:    filepath.Join(frameworks, "libmixed.a"),
+			Archive: true,
+		},
+		{Path: filepath.Join(frameworks, "libthin.a")},
+		{Path: filepath.Join(frameworks, "truncated")},
+		{
+			Path:    filepath.Join(macos, "App"),
+			Fat:     true,
+			Arches:  []string{"x86_64", "arm64"},
+			Missing: []string{},
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d results got %d", len(want), len(got))
+	}
+	for i := range want {
+		if want[i].Arches == nil {
+			if got[i].Err == nil {
+				t.Errorf("%s: want an error", got[i].Path)
+			}
+			got[i].Err = nil
+		}
+		if !reflect.DeepEqual(want[i], got[i]) {
+			t.Errorf("want %+v got %+v", want[i], got[i])
+		}
+	}
+
+	// same as -info except the path
+	last := got[len(got)-1]
+	wantInfo := strings.Replace(p.Info(t, p.FatBin), p.FatBin, last.Path, 1)
+	if info := last.Info() + "\n"; info != wantInfo {
+		t.Errorf("want %s got %s", wantInfo, info)
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, info.Mode().Perm()); err != nil {
+		t.Fatal(err)
+	}
+}
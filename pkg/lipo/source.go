@@ -0,0 +1,194 @@
+package lipo
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// defaultPerm is the permission of outputs created from sources without a mode
+const defaultPerm fs.FileMode = 0755
+
+// Source is an in-memory or any other input which is not a file.
+// Operations refer to the source by Name instead of a path.
+type Source struct {
+	Name string
+	io.ReaderAt
+	Size int64
+	// Mode is the permission of outputs created from the source
+	Mode fs.FileMode
+}
+
+func NewSource(name string, r io.ReaderAt, size int64) *Source {
+	return &Source{Name: name, ReaderAt: r, Size: size, Mode: defaultPerm}
+}
+
+func (s *Source) perm() fs.FileMode {
+	if s.Mode == 0 {
+		return defaultPerm
+	}
+	return s.Mode.Perm()
+}
+
+// file is an opened input
+type file interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+	Name() string
+	Stat() (fs.FileInfo, error)
+}
+
+var _ file = &sourceFile{}
+
+type sourceFile struct {
+	*io.SectionReader
+	src *Source
+}
+
+func (f *sourceFile) Name() string {
+	return f.src.Name
+}
+
+func (f *sourceFile) Close() error {
+	return nil
+}
+
+func (f *sourceFile) Stat() (fs.FileInfo, error) {
+	return &sourceFileInfo{src: f.src}, nil
+}
+
+type sourceFileInfo struct {
+	src *Source
+}
+
+func (i *sourceFileInfo) Name() string       { return i.src.Name }
+func (i *sourceFileInfo) Size() int64        { return i.src.Size }
+func (i *sourceFileInfo) Mode() fs.FileMode  { return i.src.perm() }
+func (i *sourceFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *sourceFileInfo) IsDir() bool        { return false }
+func (i *sourceFileInfo) Sys() any           { return nil }
+
+// tempFile is a temporary file which is removed on Close
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
+
+// spool copies the reader to a temporary file since inputs require io.ReaderAt
+func spool(r io.Reader) (*tempFile, int64, error) {
+	f, err := os.CreateTemp("", "lipo-spool-")
+	if err != nil {
+		return nil, 0, err
+	}
+	tmp := &tempFile{File: f}
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return nil, 0, err
+	}
+	return tmp, n, nil
+}
+
+// Close closes files opened for sources such as zip files and removes temporary files of them and stdin
+func (l *Lipo) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	errs := []error{}
+	if l.stdin != nil {
+		errs = append(errs, l.stdin.Close())
+	}
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
+	}
+	l.closers = nil
+	return errors.Join(errs...)
+}
+
+// own closes the closer on Close
+func (l *Lipo) own(c io.Closer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closers = append(l.closers, c)
+}
+
+func (l *Lipo) hasSource(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.sources[name]
+	return ok
+}
+
+func (l *Lipo) addSources(srcs ...*Source) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sources == nil {
+		l.sources = map[string]*Source{}
+	}
+	for _, src := range srcs {
+		l.sources[src.Name] = src
+	}
+}
+
+// source returns the source of the name. `-` is stdin and `<zip>(<member>)` is a member of the zip file.
+// The second value is false if the name is a path.
+func (l *Lipo) source(name string) (*Source, bool, error) {
+	l.mu.Lock()
+	src, ok := l.sources[name]
+	l.mu.Unlock()
+	if ok {
+		return src, true, nil
+	}
+
+	if name == stdioPath {
+		src, err := l.stdin.source()
+		return src, err == nil, err
+	}
+
+	if zipPath, member, ok := parseZipMemberName(name); ok {
+		// a file of the name takes precedence
+		if _, err := os.Stat(name); err == nil {
+			return nil, false, nil
+		}
+		src, err := l.openZipMember(zipPath, member)
+		if err != nil {
+			return nil, false, err
+		}
+		l.addSources(src)
+		return src, true, nil
+	}
+	return nil, false, nil
+}
+
+// open opens the source of the name or the file of the path
+func (l *Lipo) open(name string) (file, error) {
+	src, ok, err := l.source(name)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &sourceFile{SectionReader: io.NewSectionReader(src, 0, src.Size), src: src}, nil
+	}
+	return os.Open(frameworkBinary(name))
+}
+
+func (l *Lipo) perm(name string) (fs.FileMode, error) {
+	src, ok, err := l.source(name)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return src.perm(), nil
+	}
+	info, err := os.Stat(frameworkBinary(name))
+	if err != nil {
+		return 0, err
+	}
+	perm := info.Mode().Perm() & 07777
+	return perm, nil
+}
@@ -0,0 +1,104 @@
+package lipo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_Source(t *testing.T) {
+	t.Run("-create from sources to a destination", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		srcs := []*lipo.Source{
+			newSource(t, p.Bin(t, "x86_64")),
+			newSource(t, p.Bin(t, "arm64")),
+		}
+
+		dst := &bytes.Buffer{}
+		l := lipo.New(lipo.WithSources(srcs...), lipo.WithDestination(dst))
+		if err := l.Create(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		writeFile(t, got, dst.Bytes())
+		testlipo.DiffSha256(t, p.FatBin, got)
+	})
+
+	t.Run("-thin from a source to a destination at", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		f, err := os.Create(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		l := lipo.New(lipo.WithSources(newSource(t, p.FatBin)), lipo.WithDestinationAt(f))
+		if err := l.Thin("arm64"); err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "arm64")
+		testlipo.DiffSha256(t, want, got)
+	})
+
+	t.Run("destination without a temporary file", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		// the destination is written directly without staging in the temporary directory
+		t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "not-found"))
+
+		dst := &bytes.Buffer{}
+		l := lipo.New(lipo.WithSources(newSource(t, p.FatBin)), lipo.WithDestination(dst))
+		if err := l.Thin("arm64"); err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		writeFile(t, got, dst.Bytes())
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "arm64")
+		testlipo.DiffSha256(t, want, got)
+	})
+
+	t.Run("-replace with a source", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		l := lipo.New(lipo.WithSources(newSource(t, p.FatBin)), lipo.WithOutput(got))
+		ri := []*lipo.ReplaceInput{{Arch: "arm64", Source: newSource(t, p.NewArchBin(t, "arm64"))}}
+		if err := l.Replace(ri); err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Replace(t, want, p.FatBin, [][2]string{{"arm64", p.NewArchBin(t, "arm64")}})
+		testlipo.DiffSha256(t, want, got)
+	})
+
+	t.Run("-info with a source", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		src := newSource(t, p.FatBin)
+		src.Name = "in-memory"
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		lipo.New(lipo.WithSources(src)).Info(stdout, stderr)
+		want := "Architectures in the fat file: in-memory are: x86_64 arm64\n"
+		if got := stdout.String(); got != want {
+			t.Errorf("want %q got %q, stderr: %s", want, got, stderr.String())
+		}
+	})
+}
+
+func newSource(t *testing.T, p string) *lipo.Source {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lipo.NewSource(filepath.Base(p), bytes.NewReader(data), int64(len(data)))
+}
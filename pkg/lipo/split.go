@@ -0,0 +1,267 @@
+package lipo
+
+import (
+	"context"
+	"debug/macho"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/util"
+)
+
+// ManifestName is a file name of the manifest written by Split
+const ManifestName = "manifest.json"
+
+// Manifest presents a layout of a split fat file.
+// The order of Arches is the order of fat arch headers.
+type Manifest struct {
+	Fat64  bool            `json:"fat64"`
+	Mode   fs.FileMode     `json:"mode"`
+	Arches []*ManifestArch `json:"arches"`
+}
+
+type ManifestArch struct {
+	Arch   string        `json:"arch"`
+	File   string        `json:"file"`
+	CPU    lmacho.Cpu    `json:"cputype"`
+	SubCPU lmacho.SubCpu `json:"cpusubtype"`
+	Align  uint32        `json:"align"`
+	Hidden bool          `json:"hidden"`
+	Offset uint64        `json:"offset"`
+	Size   uint64        `json:"size"`
+}
+
+// Split writes every architecture of the fat file to `dir` and a manifest to recreate the fat file by Join
+func (l *Lipo) Split(dir string) (*Manifest, error) {
+	return l.SplitContext(context.Background(), dir)
+}
+
+// SplitContext is Split which stops writing architectures when the context is done
+func (l *Lipo) SplitContext(ctx context.Context, dir string) (*Manifest, error) {
+	if err := validateOneInput(l.in); err != nil {
+		return nil, err
+	}
+
+	fatBin := l.in[0]
+	perm, err := l.perm(fatBin)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := l.open(fatBin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ff, err := lmacho.NewFatFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Fat64:  ff.Magic == lmacho.MagicFat64,
+		Mode:   perm,
+		Arches: make([]*ManifestArch, 0, len(ff.Arches)),
+	}
+	base := filepath.Base(fatBin)
+	if fatBin == stdioPath {
+		base = "stdin"
+	}
+	seen := map[string]struct{}{}
+	for i, fa := range ff.Arches {
+		name := fmt.Sprintf("%s.%s", base, fa.CPUString())
+		if _, ok := seen[name]; ok {
+			name = fmt.Sprintf("%s.%d", name, i)
+		}
+		seen[name] = struct{}{}
+
+		if err := writeSlice(ctx, filepath.Join(dir, name), fa, perm); err != nil {
+			return nil, err
+		}
+
+		m.Arches = append(m.Arches, &ManifestArch{
+			Arch:   fa.CPUString(),
+			File:   name,
+			CPU:    fa.CPU(),
+			SubCPU: fa.SubCPU(),
+			Align:  fa.Align(),
+			Hidden: fa.Hidden,
+			Offset: fa.Offset(),
+			Size:   fa.Size(),
+		})
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeSlice(ctx context.Context, path string, fa *lmacho.FatArch, perm os.FileMode) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := lmacho.CopyObjectContext(ctx, out, fa, nil); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// Join recreates a fat file from the manifest written by Split.
+// The order, hidden architectures and the fat magic of the manifest are used instead of the options.
+func (l *Lipo) Join(manifest string) error {
+	return l.JoinContext(context.Background(), manifest)
+}
+
+// JoinContext is Join which stops writing the output when the context is done
+func (l *Lipo) JoinContext(ctx context.Context, manifest string) error {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("invalid manifest %s: %w", manifest, err)
+	}
+
+	if len(m.Arches) == 0 {
+		return fmt.Errorf("invalid manifest %s: no architectures", manifest)
+	}
+
+	dir := filepath.Dir(manifest)
+	arches := make([]Arch, 0, len(m.Arches))
+	defer func() { close(arches...) }()
+	for _, ma := range m.Arches {
+		// do not read files outside of the directory
+		if !filepath.IsLocal(ma.File) {
+			return fmt.Errorf("invalid manifest %s: %s is not a file in the directory", manifest, ma.File)
+		}
+		a, err := openManifestArch(filepath.Join(dir, ma.File), ma)
+		if err != nil {
+			return err
+		}
+		arches = append(arches, a)
+	}
+
+	// the manifest determines the order, hidden architectures and the fat magic
+	l.order = util.Map(m.Arches, func(ma *ManifestArch) string { return ma.Arch })
+	l.hidden = util.Map(util.Filter(m.Arches, func(ma *ManifestArch) bool { return ma.Hidden }),
+		func(ma *ManifestArch) string { return ma.Arch })
+	l.fat64 = m.Fat64
+	l.hideArm64 = false
+
+	layout, err := lmacho.NewFatLayout(arches, l.fat64, l.hideArm64,
+		lmacho.WithOrder(l.order...), lmacho.WithHidden(l.hidden...))
+	if err != nil {
+		return err
+	}
+	for i, fa := range layout.Arches {
+		if want := m.Arches[i].Offset; want != fa.Offset() {
+			return fmt.Errorf("planned offset %d of %s does not match offset %d in the manifest", fa.Offset(), fa.CPUString(), want)
+		}
+	}
+
+	return l.createFatBinary(ctx, arches, m.Mode.Perm())
+}
+
+func openManifestArch(p string, ma *ManifestArch) (Arch, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if uint64(info.Size()) != ma.Size {
+		f.Close()
+		return nil, fmt.Errorf("size of %s is %d bytes but the manifest records %d bytes", p, info.Size(), ma.Size)
+	}
+
+	obj := &rawObject{
+		cpu:    ma.CPU,
+		subCpu: ma.SubCPU,
+		align:  ma.Align,
+		sr:     io.NewSectionReader(f, 0, info.Size()),
+	}
+	if obj.CPUString() != ma.Arch {
+		f.Close()
+		return nil, fmt.Errorf("cputype (%d) and cpusubtype (%d) of %s do not match %s in the manifest", ma.CPU, ma.SubCPU, p, ma.Arch)
+	}
+
+	return &arch{
+		Object:       obj,
+		name:         p,
+		updatedAlign: ma.Align,
+		Closer:       f,
+	}, nil
+}
+
+var _ lmacho.Object = &rawObject{}
+
+// rawObject presents an object whose cpu and alignment are given instead of parsing it
+type rawObject struct {
+	cpu    lmacho.Cpu
+	subCpu lmacho.SubCpu
+	align  uint32
+	sr     *io.SectionReader
+}
+
+func (o *rawObject) CPU() lmacho.Cpu {
+	return o.cpu
+}
+
+func (o *rawObject) SubCPU() lmacho.SubCpu {
+	return o.subCpu
+}
+
+func (o *rawObject) Size() uint64 {
+	return uint64(o.sr.Size())
+}
+
+func (o *rawObject) Align() uint32 {
+	return o.align
+}
+
+func (o *rawObject) Type() macho.Type {
+	return 0
+}
+
+func (o *rawObject) CPUString() string {
+	return lmacho.ToCpuString(o.CPU(), o.SubCPU())
+}
+
+func (o *rawObject) Outer() (io.ReaderAt, int64, int64) {
+	return o.sr.Outer()
+}
+
+func (o *rawObject) Read(p []byte) (int, error) {
+	return o.sr.Read(p)
+}
+
+func (o *rawObject) ReadAt(p []byte, off int64) (int, error) {
+	return o.sr.ReadAt(p, off)
+}
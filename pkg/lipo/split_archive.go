@@ -0,0 +1,167 @@
+package lipo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konoui/lipo/pkg/ar"
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+// ArchiveSlice presents the thin archive of an architecture written by SplitArchive
+type ArchiveSlice struct {
+	Arch    string
+	Path    string
+	Members []string
+}
+
+type archiveMember struct {
+	hdr ar.Header
+	sr  *io.SectionReader
+}
+
+// SplitArchive writes a thin archive with the regenerated symbol table for every architecture of members of the input archive to `dir`.
+// Members of different architectures and fat members are allowed. If the output is specified, the thin archives are combined into a fat file.
+func (l *Lipo) SplitArchive(dir string) ([]*ArchiveSlice, error) {
+	return l.SplitArchiveContext(context.Background(), dir)
+}
+
+// SplitArchiveContext is SplitArchive which stops writing the output when the context is done
+func (l *Lipo) SplitArchiveContext(ctx context.Context, dir string) ([]*ArchiveSlice, error) {
+	if err := validateOneInput(l.in); err != nil {
+		return nil, err
+	}
+
+	in := l.in[0]
+	perm, err := l.perm(in)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := l.open(in)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	files, err := ar.NewArchive(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in, err)
+	}
+
+	g := newMemberGroups()
+	if err := g.addArchive(in, files); err != nil {
+		return nil, err
+	}
+	if len(g.order) == 0 {
+		return nil, &lmacho.FormatError{Err: errors.New("no object in the archive")}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(in), ".a")
+	results := make([]*ArchiveSlice, 0, len(g.order))
+	for _, cpu := range g.order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		s := &ArchiveSlice{Arch: cpu, Path: filepath.Join(dir, fmt.Sprintf("%s.%s.a", base, cpu))}
+		for _, m := range g.groups[cpu] {
+			s.Members = append(s.Members, m.hdr.Name)
+		}
+		if err := writeArchive(s.Path, g.groups[cpu], perm); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+
+	if l.out == "" {
+		return results, nil
+	}
+
+	inputs := make([]*ArchInput, len(results))
+	for i, s := range results {
+		inputs[i] = &ArchInput{Bin: s.Path}
+	}
+	arches, err := l.openArches(inputs, MergeError)
+	if err != nil {
+		return nil, err
+	}
+	defer close(arches...)
+
+	if err := l.createFatBinary(ctx, arches, perm); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// memberGroups groups members by architectures in the order of appearance
+type memberGroups struct {
+	groups map[string][]*archiveMember
+	order  []string
+}
+
+func newMemberGroups() *memberGroups {
+	return &memberGroups{groups: map[string][]*archiveMember{}}
+}
+
+func (g *memberGroups) add(cpu string, m *archiveMember) {
+	if _, ok := g.groups[cpu]; !ok {
+		g.order = append(g.order, cpu)
+	}
+	g.groups[cpu] = append(g.groups[cpu], m)
+}
+
+// addArchive adds members of the archive. Fat members are split into architectures.
+func (g *memberGroups) addArchive(p string, files []*ar.File) error {
+	for _, f := range files {
+		if f.IsSymbolTable() {
+			continue
+		}
+
+		ff, err := lmacho.NewFatFile(f.SectionReader)
+		if err == nil {
+			for _, fa := range ff.Arches {
+				g.add(fa.CPUString(), &archiveMember{hdr: f.Header, sr: io.NewSectionReader(fa, 0, int64(fa.Size()))})
+			}
+			continue
+		}
+
+		m, err := lmacho.NewArch(f.SectionReader)
+		if err != nil {
+			return &lmacho.FormatError{Err: fmt.Errorf("archive member %s(%s) is not macho file: %w", p, f.Name, err)}
+		}
+		g.add(m.CPUString(), &archiveMember{hdr: f.Header, sr: f.SectionReader})
+	}
+	return nil
+}
+
+// writeArchive writes members to the archive with the regenerated symbol table
+func writeArchive(p string, members []*archiveMember, perm os.FileMode) error {
+	out, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := ar.NewWriter(out, ar.WithSymbolTable())
+	for _, m := range members {
+		hdr := m.hdr
+		hdr.Size = m.sr.Size()
+		if err := w.Add(&hdr, io.NewSectionReader(m.sr, 0, hdr.Size)); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%s: %w", p, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,148 @@
+package lipo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/ar"
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func readArchive(t *testing.T, p string) []*ar.File {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ar.NewArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ar.ValidateSymbolTable(files); err != nil {
+		t.Errorf("%s: %v", p, err)
+	}
+	return files
+}
+
+func readMember(t *testing.T, f *ar.File) []byte {
+	t.Helper()
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, f.SectionReader.Size()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLipo_SplitArchive(t *testing.T) {
+	t.Run("mixed", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "fat.a")
+		l := lipo.New(lipo.WithInputs("../ar/testdata/arm64-amd64-func12.a"), lipo.WithOutput(out))
+		got, err := l.SplitArchive(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []*lipo.ArchiveSlice{
+			{Arch: "x86_64", Path: filepath.Join(dir, "arm64-amd64-func12.x86_64.a"), Members: []string{"amd64-func1.o", "amd64-func2.o"}},
+			{Arch: "arm64", Path: filepath.Join(dir, "arm64-amd64-func12.arm64.a"), Members: []string{"arm64-func1.o", "arm64-func2.o"}},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %v got %v", want, got)
+		}
+
+		// the symbol tables are the same as libtool
+		for i, libtool := range []string{"../ar/testdata/amd64-func12.a", "../ar/testdata/arm64-func12.a"} {
+			wantFiles, gotFiles := readArchive(t, libtool), readArchive(t, want[i].Path)
+			if wantFiles[0].Name != gotFiles[0].Name || !bytes.Equal(readMember(t, wantFiles[0]), readMember(t, gotFiles[0])) {
+				t.Errorf("symbol table of %s does not match %s", want[i].Path, libtool)
+			}
+		}
+
+		verifyArches(t, out, "x86_64", "arm64")
+		p := testlipo.NewLipoBin(t)
+		wantFat := filepath.Join(dir, "fat-want.a")
+		p.Create(t, wantFat, want[0].Path, want[1].Path)
+		testlipo.DiffSha256(t, wantFat, out)
+	})
+
+	t.Run("fat member", func(t *testing.T) {
+		dir := t.TempDir()
+		fatObj := filepath.Join(dir, "func1.o")
+		objs := []string{"../ar/testdata/arm64-func1.o", "../ar/testdata/amd64-func1.o"}
+		if err := lipo.New(lipo.WithInputs(objs...), lipo.WithOutput(fatObj)).Create(); err != nil {
+			t.Fatal(err)
+		}
+
+		in := filepath.Join(dir, "libfunc.a")
+		f, err := os.Create(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := ar.NewWriter(f)
+		for _, p := range []string{fatObj, "../ar/testdata/arm64-func2.o"} {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Add(&ar.Header{Name: filepath.Base(p), Size: int64(len(data)), Mode: 0100644}, bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		got, err := lipo.New(lipo.WithInputs(in)).SplitArchive(filepath.Join(dir, "out"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*lipo.ArchiveSlice{
+			// in the order of the fat member
+			{Arch: "x86_64", Path: filepath.Join(dir, "out", "libfunc.x86_64.a"), Members: []string{"func1.o"}},
+			{Arch: "arm64", Path: filepath.Join(dir, "out", "libfunc.arm64.a"), Members: []string{"func1.o", "arm64-func2.o"}},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %v got %v", want, got)
+		}
+
+		for i, obj := range []string{objs[1], objs[0]} {
+			files := readArchive(t, want[i].Path)
+			wantData, err := os.ReadFile(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(wantData, readMember(t, files[1])) {
+				t.Errorf("%s(%s) does not match %s", want[i].Path, files[1].Name, obj)
+			}
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		l := lipo.New(lipo.WithInputs("../ar/testdata/arm64-amd64-func12.a"))
+		if _, err := l.SplitArchiveContext(ctx, dir); !errors.Is(err, context.Canceled) {
+			t.Fatalf("want %v got %v", context.Canceled, err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("files are left: %v", entries)
+		}
+	})
+
+	t.Run("not archive", func(t *testing.T) {
+		_, err := lipo.New(lipo.WithInputs("../ar/testdata/arm64-func1.o")).SplitArchive(t.TempDir())
+		if err == nil {
+			t.Error("want an error")
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package lipo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_SplitJoin(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputs    []string
+		hideArm64 bool
+		fat64     bool
+	}{
+		{
+			name:   "split and join",
+			inputs: []string{"arm64", "x86_64", "arm64e"},
+		},
+		{
+			name:      "split and join hideARM64",
+			inputs:    []string{"armv7k", "arm64"},
+			hideArm64: true,
+		},
+		{
+			name:   "split and join fat64",
+			inputs: []string{"arm64", "x86_64"},
+			fat64:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testlipo.Setup(t, bm, tt.inputs,
+				testlipo.WithHideArm64(tt.hideArm64),
+				testlipo.WithFat64(tt.fat64),
+			)
+			testSplitJoin(t, p.FatBin, len(tt.inputs))
+		})
+	}
+
+	t.Run("archive", func(t *testing.T) {
+		testSplitJoin(t, "../ar/testdata/fat-arm64-amd64-func1", 2)
+	})
+
+	t.Run("file outside of the directory", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		m, err := lipo.New(lipo.WithInputs(p.FatBin)).Split(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range []string{"../" + m.Arches[0].File, filepath.Join(dir, m.Arches[0].File)} {
+			m.Arches[0].File = file
+			data, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest := filepath.Join(dir, lipo.ManifestName)
+			writeFile(t, manifest, data)
+
+			err = lipo.New(lipo.WithOutput(filepath.Join(t.TempDir(), "joined"))).Join(manifest)
+			if err == nil || !strings.Contains(err.Error(), "is not a file in the directory") {
+				t.Errorf("%s: unexpected error %v", file, err)
+			}
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := lipo.New(lipo.WithInputs(p.FatBin)).SplitContext(ctx, t.TempDir()); !errors.Is(err, context.Canceled) {
+			t.Errorf("want context.Canceled got %v", err)
+		}
+	})
+}
+
+func testSplitJoin(t *testing.T, fatBin string, n int) {
+	t.Helper()
+	dir := t.TempDir()
+	m, err := lipo.New(lipo.WithInputs(fatBin)).Split(dir)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if len(m.Arches) != n {
+		t.Fatalf("want %d arches got %d", n, len(m.Arches))
+	}
+	for _, a := range m.Arches {
+		if _, err := os.Stat(filepath.Join(dir, a.File)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := filepath.Join(t.TempDir(), "joined")
+	l := lipo.New(lipo.WithOutput(got))
+	if err := l.Join(filepath.Join(dir, lipo.ManifestName)); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	testlipo.DiffSha256(t, fatBin, got)
+}
@@ -0,0 +1,44 @@
+package lipo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// stdioPath presents stdin as an input or stdout as an output
+const stdioPath = "-"
+
+// stdin spools the whole input to a temporary file which is removed on Close
+// since inputs require io.ReaderAt and stdin may not be seekable.
+type stdin struct {
+	mu  sync.Mutex
+	r   io.Reader
+	src *Source
+	tmp *tempFile
+}
+
+func (s *stdin) source() (*Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.src == nil {
+		tmp, size, err := spool(s.r)
+		if err != nil {
+			return nil, fmt.Errorf("can't read stdin: %w", err)
+		}
+		s.tmp = tmp
+		s.src = NewSource(stdioPath, tmp, size)
+	}
+	return s.src, nil
+}
+
+func (s *stdin) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tmp == nil {
+		return nil
+	}
+	err := s.tmp.Close()
+	s.tmp, s.src = nil, nil
+	return err
+}
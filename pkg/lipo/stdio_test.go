@@ -0,0 +1,127 @@
+package lipo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func TestLipo_Stdio(t *testing.T) {
+	t.Run("-thin from stdin to stdout", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		stdin, err := os.Open(p.FatBin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+
+		stdout := &bytes.Buffer{}
+		l := lipo.New(
+			lipo.WithInputs("-"), lipo.WithOutput("-"),
+			lipo.WithStdin(stdin), lipo.WithStdout(stdout))
+		if err := l.Thin("arm64"); err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		writeFile(t, got, stdout.Bytes())
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "arm64")
+		testlipo.DiffSha256(t, want, got)
+	})
+
+	t.Run("-extract from stdin to stdout", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64", "arm64e"})
+		stdin, err := os.Open(p.FatBin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+
+		stdout := &bytes.Buffer{}
+		l := lipo.New(
+			lipo.WithInputs("-"), lipo.WithOutput("-"),
+			lipo.WithStdin(stdin), lipo.WithStdout(stdout))
+		if err := l.Extract("arm64", "x86_64"); err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		writeFile(t, got, stdout.Bytes())
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Extract(t, want, p.FatBin, []string{"arm64", "x86_64"})
+		testlipo.DiffSha256(t, want, got)
+	})
+
+	t.Run("-create from stdin and a file", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		data, err := os.ReadFile(p.Bin(t, "arm64"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := filepath.Join(p.Dir, gotName(t))
+		l := lipo.New(
+			lipo.WithInputs(p.Bin(t, "x86_64"), "-"), lipo.WithOutput(got),
+			lipo.WithStdin(bytes.NewReader(data)))
+		if err := l.Create(); err != nil {
+			t.Fatal(err)
+		}
+		verifyArches(t, got, "x86_64", "arm64")
+	})
+
+	t.Run("-archs from stdin", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		data, err := os.ReadFile(p.FatBin)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l := lipo.New(lipo.WithInputs("-"), lipo.WithStdin(bytes.NewReader(data)))
+		got, err := l.Archs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := p.Archs(t, p.FatBin)
+		if w := strings.Join(got, " "); w != want {
+			t.Errorf("want %s got %s", want, w)
+		}
+	})
+}
+
+func TestLipo_StdinSpool(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+	data, err := os.ReadFile(p.FatBin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stdin is spooled to a temporary file which is removed on Close
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	l := lipo.New(lipo.WithInputs("-"), lipo.WithStdin(bytes.NewReader(data)))
+	if _, err := l.Archs(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 1 {
+		t.Errorf("want a spooled file got %v", entries)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("temporary files are not removed: %v", entries)
+	}
+}
+
+func writeFile(t *testing.T, p string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(p, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
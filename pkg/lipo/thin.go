@@ -1,6 +1,7 @@
 package lipo
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,11 @@ import (
 )
 
 func (l *Lipo) Thin(arch string) error {
+	return l.ThinContext(context.Background(), arch)
+}
+
+// ThinContext is Thin which stops writing the output when the context is done
+func (l *Lipo) ThinContext(ctx context.Context, arch string) error {
 	if err := validateOneInput(l.in); err != nil {
 		return err
 	}
@@ -18,12 +24,12 @@ func (l *Lipo) Thin(arch string) error {
 	}
 
 	fatBin := l.in[0]
-	perm, err := perm(fatBin)
+	perm, err := l.perm(fatBin)
 	if err != nil {
 		return err
 	}
 
-	ff, err := OpenFatFile(fatBin)
+	ff, err := l.openFatFile(fatBin)
 	if err != nil {
 		return err
 	}
@@ -34,31 +40,19 @@ func (l *Lipo) Thin(arch string) error {
 		return fmt.Errorf("fat input file (%s) does not contain the specified architecture (%s) to thin it to", fatBin, arch)
 	}
 
-	return l.thin(perm, extracted[0])
+	return l.thin(ctx, perm, extracted[0])
 }
 
-func (l *Lipo) thin(perm os.FileMode, arch Arch) error {
-	out, err := createTemp(l.out)
-	if err != nil {
-		return err
+func (l *Lipo) thin(ctx context.Context, perm os.FileMode, arch Arch) error {
+	if lmacho.IsBlank(arch) {
+		return fmt.Errorf("fat input file contains the specified architecture (%s) as a blank entry, can't thin it", arch.CPUString())
 	}
 
-	if _, err := io.CopyN(out, arch, int64(arch.Size())); err != nil {
-		return fmt.Errorf("error write binary data: %w", err)
-	}
-
-	if err := out.Chmod(perm); err != nil {
-		return err
-	}
-
-	if err := out.Sync(); err != nil {
-		return err
-	}
-
-	// close before rename
-	if err := out.Close(); err != nil {
-		return err
+	if l.dryRun != nil {
+		return printThinPlan(l.dryRun, l.out, arch)
 	}
 
-	return os.Rename(out.Name(), l.out)
+	return l.writeOutput(perm,
+		func(w io.Writer) error { return lmacho.CopyObjectContext(ctx, w, arch, l.progress) },
+		func(f *os.File) error { return verifyThin(f, arch) })
 }
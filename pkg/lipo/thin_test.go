@@ -63,6 +63,21 @@ func TestLipo_ThinWithOverwriteInput(t *testing.T) {
 	})
 }
 
+func TestLipo_ThinWithVerifyOutput(t *testing.T) {
+	t.Run("verify-output", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"x86_64", "arm64"})
+		got := filepath.Join(p.Dir, gotName(t))
+		l := lipo.New(lipo.WithInputs(p.FatBin), lipo.WithOutput(got), lipo.WithVerifyOutput())
+		if err := l.Thin("arm64"); err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "arm64")
+		diffSha256(t, want, got)
+	})
+}
+
 func TestLipo_ThinError(t *testing.T) {
 	t.Run("not-match-arch", func(t *testing.T) {
 		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
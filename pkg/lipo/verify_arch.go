@@ -2,16 +2,20 @@ package lipo
 
 import "github.com/konoui/lipo/pkg/util"
 
+// VerifyArch returns true if the input contains all of the architectures.
+// Every member must contain them if the input is a zip file.
 func (l *Lipo) VerifyArch(arches ...string) (bool, error) {
-	gotArches, err := l.Archs()
+	all, err := l.archsAll()
 	if err != nil {
 		return false, err
 	}
 
-	m := util.ExistenceMap(gotArches, func(a string) string { return a })
-	for _, a := range arches {
-		if _, ok := m[a]; !ok {
-			return false, nil
+	for _, gotArches := range all {
+		m := util.ExistenceMap(gotArches, func(a string) string { return a })
+		for _, a := range arches {
+			if _, ok := m[a]; !ok {
+				return false, nil
+			}
 		}
 	}
 	return true, nil
@@ -0,0 +1,86 @@
+package lipo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+)
+
+var errVerifyOutput = errors.New("output verification failed")
+
+// verifyFat reads back the written fat file and compares it with the planned layout
+func verifyFat(f *os.File, layout *lmacho.FatLayout[Arch]) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if want := int64(layout.Size()); info.Size() != want {
+		return fmt.Errorf("%w: size of %s want: %d bytes, got: %d bytes", errVerifyOutput, f.Name(), want, info.Size())
+	}
+
+	ff, err := lmacho.NewFatFile(f)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errVerifyOutput, err)
+	}
+
+	if ff.FatHeader != layout.FatHeader {
+		return fmt.Errorf("%w: fat header want: %+v, got: %+v", errVerifyOutput, layout.FatHeader, ff.FatHeader)
+	}
+
+	if len(ff.Arches) != len(layout.Arches) {
+		return fmt.Errorf("%w: number of architectures want: %d, got: %d", errVerifyOutput, len(layout.Arches), len(ff.Arches))
+	}
+
+	for i, got := range ff.Arches {
+		want := layout.Arches[i]
+		if got.CPU() != want.CPU() || got.SubCPU() != want.SubCPU() ||
+			got.Offset() != want.Offset() || got.Size() != want.Size() ||
+			got.Align() != want.Align() || got.Hidden != want.Hidden {
+			return fmt.Errorf("%w: fat arch header of %s does not match the planned layout", errVerifyOutput, want.CPUString())
+		}
+
+		if err := compare(layout.Objects[i], got, int64(want.Size())); err != nil {
+			return fmt.Errorf("%w: %s (%s): %w", errVerifyOutput, want.CPUString(), layout.Objects[i].Name(), err)
+		}
+	}
+	return nil
+}
+
+// verifyThin reads back the written thin file and compares it with the source
+func verifyThin(f *os.File, arch Arch) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if want := int64(arch.Size()); info.Size() != want {
+		return fmt.Errorf("%w: size of %s want: %d bytes, got: %d bytes", errVerifyOutput, f.Name(), want, info.Size())
+	}
+
+	if err := compare(arch, f, info.Size()); err != nil {
+		return fmt.Errorf("%w: %s (%s): %w", errVerifyOutput, arch.CPUString(), arch.Name(), err)
+	}
+	return nil
+}
+
+func compare(src, written io.ReaderAt, size int64) error {
+	want, err := sha256Sum(src, size)
+	if err != nil {
+		return fmt.Errorf("read source: %w", err)
+	}
+
+	got, err := sha256Sum(written, size)
+	if err != nil {
+		return fmt.Errorf("read output: %w", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		return fmt.Errorf("sha256 want: %x, got: %x", want, got)
+	}
+	return nil
+}
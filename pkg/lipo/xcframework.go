@@ -0,0 +1,460 @@
+package lipo
+
+import (
+	"cmp"
+	"context"
+	"debug/macho"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/plist"
+)
+
+// XCFrameworkInfoPlist is a file name of the property list of an xcframework
+const XCFrameworkInfoPlist = "Info.plist"
+
+// XCFrameworkLibrary presents a library of AvailableLibraries in Info.plist of an xcframework
+type XCFrameworkLibrary struct {
+	// Identifier is LibraryIdentifier e.g. ios-arm64_x86_64-simulator
+	Identifier string
+	// Path is LibraryPath e.g. libfoo.a or Foo.framework
+	Path string
+	// BinaryPath is the path of the binary relative to the library directory e.g. Foo.framework/Foo
+	BinaryPath string
+	Arches     []string
+	Platform   lmacho.Platform
+	// Err is a mismatch between Info.plist and the binary found by InspectXCFramework
+	Err error
+}
+
+// Info returns a line of the identifier, the platform and the architectures
+func (lib *XCFrameworkLibrary) Info() string {
+	return fmt.Sprintf("%s: %s is architecture: %s", lib.Identifier, lib.Platform, strings.Join(lib.Arches, " "))
+}
+
+func xcframeworkIdentifier(p lmacho.Platform, arches []string) string {
+	id := p.OS() + "-" + strings.Join(arches, "_")
+	if v := p.Variant(); v != "" {
+		id += "-" + v
+	}
+	return id
+}
+
+func (lib *XCFrameworkLibrary) plist() map[string]any {
+	m := map[string]any{
+		"LibraryIdentifier":      lib.Identifier,
+		"LibraryPath":            lib.Path,
+		"BinaryPath":             lib.BinaryPath,
+		"SupportedArchitectures": lib.Arches,
+		"SupportedPlatform":      lib.Platform.OS(),
+	}
+	if v := lib.Platform.Variant(); v != "" {
+		m["SupportedPlatformVariant"] = v
+	}
+	return m
+}
+
+type xcframeworkSlice struct {
+	input string
+	bin   string
+	arch  string
+}
+
+type xcframeworkGroup struct {
+	platform lmacho.Platform
+	path     string
+	slices   []*xcframeworkSlice
+}
+
+// CreateXCFramework creates the xcframework directory of the output from the libraries or the frameworks of the inputs.
+// Slices of the same platform are merged into a fat file and Info.plist is generated.
+func (l *Lipo) CreateXCFramework() ([]*XCFrameworkLibrary, error) {
+	return l.CreateXCFrameworkContext(context.Background())
+}
+
+// CreateXCFrameworkContext is CreateXCFramework which stops writing the output when the context is done
+func (l *Lipo) CreateXCFrameworkContext(ctx context.Context) (_ []*XCFrameworkLibrary, err error) {
+	if len(l.in) == 0 {
+		return nil, errNoInput
+	}
+	if filepath.Ext(l.out) != ".xcframework" {
+		return nil, fmt.Errorf("output %s must have .xcframework extension", l.out)
+	}
+	if _, err := os.Lstat(l.out); err == nil {
+		return nil, fmt.Errorf("output %s already exists", l.out)
+	}
+
+	groups, err := l.groupXCFrameworkInputs(l.in)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(l.out, 0755); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// do not leave the incomplete xcframework on failure
+		if err != nil {
+			os.RemoveAll(l.out)
+		}
+	}()
+
+	libs := make([]*XCFrameworkLibrary, 0, len(groups))
+	for _, g := range groups {
+		lib, err := l.writeXCFrameworkLibrary(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		libs = append(libs, lib)
+	}
+
+	available := make([]any, len(libs))
+	for i, lib := range libs {
+		available[i] = lib.plist()
+	}
+	data, err := plist.Marshal(map[string]any{
+		"AvailableLibraries":       available,
+		"CFBundlePackageType":      "XFWK",
+		"XCFrameworkFormatVersion": "1.0",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(l.out, XCFrameworkInfoPlist), data, 0644); err != nil {
+		return nil, err
+	}
+	return libs, nil
+}
+
+// groupXCFrameworkInputs returns slices of the inputs grouped by the platform in the order of the identifiers
+func (l *Lipo) groupXCFrameworkInputs(inputs []string) ([]*xcframeworkGroup, error) {
+	byPlatform := map[lmacho.Platform]*xcframeworkGroup{}
+	framework := false
+	for i, in := range inputs {
+		bin, isFramework, err := resolveXCFrameworkInput(in)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && framework != isFramework {
+			return nil, errors.New("libraries and frameworks can not be mixed")
+		}
+		framework = isFramework
+
+		sps, err := l.slicePlatforms(bin)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sps {
+			g, ok := byPlatform[s.platform]
+			if !ok {
+				g = &xcframeworkGroup{platform: s.platform, path: filepath.Base(in)}
+				byPlatform[s.platform] = g
+			}
+			if g.path != filepath.Base(in) {
+				return nil, fmt.Errorf("%s and %s for %s must have the same name", g.slices[0].input, in, s.platform)
+			}
+			for _, gs := range g.slices {
+				if gs.arch == s.arch {
+					return nil, fmt.Errorf("%s and %s have the same architecture (%s) for %s", gs.input, in, s.arch, s.platform)
+				}
+			}
+			g.slices = append(g.slices, &xcframeworkSlice{input: in, bin: bin, arch: s.arch})
+		}
+	}
+
+	groups := make([]*xcframeworkGroup, 0, len(byPlatform))
+	for _, g := range byPlatform {
+		slices.SortFunc(g.slices, func(a, b *xcframeworkSlice) int { return cmp.Compare(a.arch, b.arch) })
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b *xcframeworkGroup) int {
+		return cmp.Compare(xcframeworkIdentifier(a.platform, a.arches()), xcframeworkIdentifier(b.platform, b.arches()))
+	})
+	return groups, nil
+}
+
+func (g *xcframeworkGroup) arches() []string {
+	arches := make([]string, len(g.slices))
+	for i, s := range g.slices {
+		arches[i] = s.arch
+	}
+	return arches
+}
+
+// resolveXCFrameworkInput returns the binary of the library or the framework
+func resolveXCFrameworkInput(in string) (string, bool, error) {
+	info, err := os.Stat(in)
+	if err != nil {
+		return "", false, err
+	}
+	if !info.IsDir() {
+		return in, false, nil
+	}
+	if filepath.Ext(in) != frameworkExt {
+		return "", false, fmt.Errorf("%s is neither a library nor a framework", in)
+	}
+	bin := frameworkBinary(in)
+	if _, err := os.Stat(bin); err != nil {
+		return "", false, fmt.Errorf("binary of framework %s not found: %w", in, err)
+	}
+	return bin, true, nil
+}
+
+func (l *Lipo) writeXCFrameworkLibrary(ctx context.Context, g *xcframeworkGroup) (*XCFrameworkLibrary, error) {
+	arches := g.arches()
+	lib := &XCFrameworkLibrary{
+		Identifier: xcframeworkIdentifier(g.platform, arches),
+		Path:       g.path,
+		BinaryPath: g.path,
+		Arches:     arches,
+		Platform:   g.platform,
+	}
+
+	dir := filepath.Join(l.out, lib.Identifier)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	first := g.slices[0]
+	isFramework := first.bin != first.input
+	if isFramework {
+		// copy the framework and overwrite the real binary to keep symbolic links of versioned frameworks
+		if err := copyTree(first.input, filepath.Join(dir, g.path)); err != nil {
+			return nil, err
+		}
+		real, err := filepath.EvalSymlinks(first.bin)
+		if err != nil {
+			return nil, err
+		}
+		base, err := filepath.EvalSymlinks(first.input)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(base, real)
+		if err != nil {
+			return nil, err
+		}
+		lib.BinaryPath = filepath.Join(g.path, rel)
+	}
+
+	out := filepath.Join(dir, lib.BinaryPath)
+	if len(g.slices) == 1 {
+		typ, err := l.inspect(first.bin)
+		if err != nil {
+			return nil, err
+		}
+		if typ != inspectFat {
+			if isFramework {
+				// already copied
+				return lib, nil
+			}
+			return lib, copyFile(first.bin, out)
+		}
+		return lib, New(WithInputs(first.bin), WithOutput(out), WithProgress(l.progress)).ThinContext(ctx, first.arch)
+	}
+
+	inputs := make([]*ArchInput, len(g.slices))
+	for i, s := range g.slices {
+		inputs[i] = &ArchInput{Arch: s.arch, Bin: s.bin}
+	}
+	return lib, New(WithArch(inputs...), WithOutput(out), WithProgress(l.progress)).CreateContext(ctx)
+}
+
+type slicePlatform struct {
+	arch     string
+	platform lmacho.Platform
+}
+
+// slicePlatforms returns architectures and platforms of the thin file, the fat file or the archive
+func (l *Lipo) slicePlatforms(bin string) ([]*slicePlatform, error) {
+	arches, err := l.openArches([]*ArchInput{{Bin: bin}}, MergeError)
+	if err != nil {
+		return nil, err
+	}
+	defer close(arches...)
+
+	ret := make([]*slicePlatform, len(arches))
+	for i, a := range arches {
+		p, err := archPlatform(a)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", bin, a.CPUString(), err)
+		}
+		ret[i] = &slicePlatform{arch: a.CPUString(), platform: p}
+	}
+	return ret, nil
+}
+
+// archPlatform returns the platform of the object or the first member of the archive
+func archPlatform(a Arch) (lmacho.Platform, error) {
+	mf, err := archMachO(a)
+	if err != nil {
+		return lmacho.PlatformUnknown, err
+	}
+	p := lmacho.BuildPlatform(mf)
+	if p == lmacho.PlatformUnknown {
+		return p, errors.New("can't figure out the platform")
+	}
+	return p, nil
+}
+
+// archMachO parses the object or the first member of the archive
+func archMachO(a Arch) (*macho.File, error) {
+	var obj io.ReaderAt = a
+	size := a.Size()
+	if archive, ok := a.(*Archive); ok {
+		obj, size = archive.Arches[0], archive.Arches[0].Size()
+	}
+	return macho.NewFile(io.NewSectionReader(obj, 0, int64(size)))
+}
+
+// InspectXCFramework reads Info.plist of the xcframework and validates it against the actual binaries.
+// A mismatch is reported as Err of each library.
+func (l *Lipo) InspectXCFramework(dir string) ([]*XCFrameworkLibrary, error) {
+	data, err := os.ReadFile(filepath.Join(dir, XCFrameworkInfoPlist))
+	if err != nil {
+		return nil, err
+	}
+	v, err := plist.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", XCFrameworkInfoPlist, err)
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: root is not a dictionary", XCFrameworkInfoPlist)
+	}
+	available, ok := root["AvailableLibraries"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: AvailableLibraries is not found", XCFrameworkInfoPlist)
+	}
+
+	libs := make([]*XCFrameworkLibrary, 0, len(available))
+	for _, v := range available {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: a library of AvailableLibraries is not a dictionary", XCFrameworkInfoPlist)
+		}
+		libs = append(libs, l.inspectXCFrameworkLibrary(dir, m))
+	}
+	return libs, nil
+}
+
+func (l *Lipo) inspectXCFrameworkLibrary(dir string, m map[string]any) *XCFrameworkLibrary {
+	str := func(k string) string { v, _ := m[k].(string); return v }
+	lib := &XCFrameworkLibrary{
+		Identifier: str("LibraryIdentifier"),
+		Path:       str("LibraryPath"),
+		BinaryPath: str("BinaryPath"),
+	}
+	if archs, ok := m["SupportedArchitectures"].([]any); ok {
+		for _, a := range archs {
+			if s, ok := a.(string); ok {
+				lib.Arches = append(lib.Arches, s)
+			}
+		}
+	}
+
+	if lib.Identifier == "" || lib.Path == "" {
+		lib.Err = errors.New("LibraryIdentifier or LibraryPath is not found")
+		return lib
+	}
+
+	p, ok := lmacho.ToPlatform(str("SupportedPlatform"), str("SupportedPlatformVariant"))
+	if !ok {
+		lib.Err = fmt.Errorf("%s: unsupported platform %s %s", lib.Identifier, str("SupportedPlatform"), str("SupportedPlatformVariant"))
+		return lib
+	}
+	lib.Platform = p
+
+	bin := filepath.Join(dir, lib.Identifier, lib.BinaryPath)
+	if lib.BinaryPath == "" {
+		bin = frameworkBinary(filepath.Join(dir, lib.Identifier, lib.Path))
+	}
+
+	sps, err := l.slicePlatforms(bin)
+	if err != nil {
+		lib.Err = err
+		return lib
+	}
+
+	actual := make([]string, 0, len(sps))
+	for _, s := range sps {
+		if s.platform != p {
+			lib.Err = fmt.Errorf("%s: %s (%s) is built for %s but Info.plist declares %s", lib.Identifier, bin, s.arch, s.platform, p)
+			return lib
+		}
+		actual = append(actual, s.arch)
+	}
+
+	if !sameArches(lib.Arches, actual) {
+		lib.Err = fmt.Errorf("%s: Info.plist declares architectures %s but %s contains %s",
+			lib.Identifier, strings.Join(lib.Arches, " "), bin, strings.Join(actual, " "))
+	}
+	return lib
+}
+
+func sameArches(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// copyTree copies the directory and keeps symbolic links as they are
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil
+		}
+	})
+}
+
+// copyFile copies the regular file with the permission
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
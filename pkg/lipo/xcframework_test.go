@@ -0,0 +1,170 @@
+package lipo_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/lmacho"
+	"github.com/konoui/lipo/pkg/plist"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+func patchedLib(t *testing.T, p *testlipo.TestLipo, dir, arch string, platform lmacho.Platform) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(dir, "libfoo")
+	testlipo.PatchPlatform(t, p.Bin(t, arch), lib, platform)
+	return lib
+}
+
+func TestLipo_CreateXCFramework(t *testing.T) {
+	t.Run("libraries", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		ios := patchedLib(t, p, filepath.Join(dir, "ios"), "arm64", lmacho.PlatformIOS)
+		simArm64 := patchedLib(t, p, filepath.Join(dir, "sim-arm64"), "arm64", lmacho.PlatformIOSSimulator)
+		simX86 := patchedLib(t, p, filepath.Join(dir, "sim-x86_64"), "x86_64", lmacho.PlatformIOSSimulator)
+		macos := filepath.Join(dir, "macos", "libfoo")
+		if err := os.MkdirAll(filepath.Dir(macos), 0755); err != nil {
+			t.Fatal(err)
+		}
+		copyFile(t, p.FatBin, macos)
+
+		out := filepath.Join(dir, "Foo.xcframework")
+		l := lipo.New(lipo.WithInputs(ios, simArm64, simX86, macos), lipo.WithOutput(out))
+		libs, err := l.CreateXCFramework()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []string{}
+		for _, lib := range libs {
+			ids = append(ids, lib.Identifier)
+		}
+		wantIDs := []string{"ios-arm64", "ios-arm64_x86_64-simulator", "macos-arm64_x86_64"}
+		if !slices.Equal(wantIDs, ids) {
+			t.Fatalf("want %v got %v", wantIDs, ids)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, lipo.XCFrameworkInfoPlist))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := plist.Unmarshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim := v.(map[string]any)["AvailableLibraries"].([]any)[1].(map[string]any)
+		if sim["SupportedPlatform"] != "ios" || sim["SupportedPlatformVariant"] != "simulator" || sim["LibraryPath"] != "libfoo" {
+			t.Errorf("unexpected library %v", sim)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Create(t, want, simArm64, simX86)
+		diffSha256(t, want, filepath.Join(out, "ios-arm64_x86_64-simulator", "libfoo"))
+		testlipo.DiffSha256(t, ios, filepath.Join(out, "ios-arm64", "libfoo"))
+
+		got, err := l.InspectXCFramework(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lib := range got {
+			if lib.Err != nil {
+				t.Errorf("unexpected error: %v", lib.Err)
+			}
+		}
+		if got[2].Info() != "macos-arm64_x86_64: macos is architecture: arm64 x86_64" {
+			t.Errorf("unexpected info: %s", got[2].Info())
+		}
+
+		// the binaries do not match Info.plist
+		copyFile(t, p.Bin(t, "arm64"), filepath.Join(out, "macos-arm64_x86_64", "libfoo"))
+		copyFile(t, p.Bin(t, "arm64"), filepath.Join(out, "ios-arm64", "libfoo"))
+		got, err = l.InspectXCFramework(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[2].Err == nil || !strings.Contains(got[2].Err.Error(), "declares architectures arm64 x86_64") {
+			t.Errorf("want a mismatch error got %v", got[2].Err)
+		}
+		if got[0].Err == nil || !strings.Contains(got[0].Err.Error(), "is built for macos but Info.plist declares ios") {
+			t.Errorf("want a platform error got %v", got[0].Err)
+		}
+	})
+
+	t.Run("versioned-framework", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		fw := filepath.Join(dir, "Foo.framework")
+		if err := os.MkdirAll(filepath.Join(fw, "Versions", "A", "Resources"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		copyFile(t, p.FatBin, filepath.Join(fw, "Versions", "A", "Foo"))
+		writeFile(t, filepath.Join(fw, "Versions", "A", "Resources", "Info.plist"), []byte("<plist></plist>"))
+		for link, target := range map[string]string{
+			"Versions/Current": "A",
+			"Foo":              "Versions/Current/Foo",
+			"Resources":        "Versions/Current/Resources",
+		} {
+			if err := os.Symlink(target, filepath.Join(fw, link)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out := filepath.Join(dir, "Foo.xcframework")
+		libs, err := lipo.New(lipo.WithInputs(fw), lipo.WithOutput(out)).CreateXCFramework()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(libs) != 1 || libs[0].BinaryPath != "Foo.framework/Versions/A/Foo" || libs[0].Path != "Foo.framework" {
+			t.Fatalf("unexpected libraries %+v", libs[0])
+		}
+
+		gotFw := filepath.Join(out, "macos-arm64_x86_64", "Foo.framework")
+		if v, err := os.Readlink(filepath.Join(gotFw, "Foo")); err != nil || v != "Versions/Current/Foo" {
+			t.Errorf("symbolic link is not kept: %s %v", v, err)
+		}
+		arches, err := lipo.New(lipo.WithInputs(filepath.Join(gotFw, "Foo"))).Archs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(arches, []string{"x86_64", "arm64"}) {
+			t.Errorf("unexpected arches %v", arches)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		dir := t.TempDir()
+		a := patchedLib(t, p, filepath.Join(dir, "a"), "arm64", lmacho.PlatformIOS)
+		b := patchedLib(t, p, filepath.Join(dir, "b"), "arm64", lmacho.PlatformIOS)
+		tests := []struct {
+			name   string
+			inputs []string
+			out    string
+			want   string
+		}{
+			{name: "same arch", inputs: []string{a, b}, out: "Foo.xcframework", want: "have the same architecture"},
+			{name: "extension", inputs: []string{a}, out: "Foo", want: "must have .xcframework extension"},
+			{name: "no input", out: "Foo.xcframework", want: "no input files specified"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				out := filepath.Join(dir, tt.out)
+				_, err := lipo.New(lipo.WithInputs(tt.inputs...), lipo.WithOutput(out)).CreateXCFramework()
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("want %s got %v", tt.want, err)
+				}
+				if _, err := os.Stat(out); err == nil {
+					t.Errorf("output should not be created")
+				}
+			})
+		}
+	})
+}
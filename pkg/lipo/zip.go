@@ -0,0 +1,316 @@
+package lipo
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// zipExts are extensions of zip files whose members can be inputs like `app.ipa(Payload/App.app/App)`
+var zipExts = []string{".zip", ".ipa"}
+
+func isZip(p string) bool {
+	return slices.Contains(zipExts, strings.ToLower(filepath.Ext(p)))
+}
+
+func zipMemberName(zipPath, member string) string {
+	return fmt.Sprintf("%s(%s)", zipPath, member)
+}
+
+func parseZipMemberName(name string) (zipPath, member string, ok bool) {
+	if !strings.HasSuffix(name, ")") {
+		return "", "", false
+	}
+	for _, ext := range zipExts {
+		if i := strings.Index(strings.ToLower(name), ext+"("); i >= 0 {
+			return name[:i+len(ext)], name[i+len(ext)+1 : len(name)-1], true
+		}
+	}
+	return "", "", false
+}
+
+// openZip opens the zip file. The file must be open while members are read.
+func openZip(p string) (*os.File, *zip.Reader, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return f, zr, nil
+}
+
+func (l *Lipo) openZipMember(zipPath, member string) (*Source, error) {
+	zf, zr, err := openZip(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	l.own(zf)
+
+	for _, f := range zr.File {
+		if f.Name == member {
+			src, c, err := zipMemberSource(zipPath, zf, f)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				l.own(c)
+			}
+			return src, nil
+		}
+	}
+	return nil, fmt.Errorf("zip file %s does not contain %s", zipPath, member)
+}
+
+// zipMemberSource returns the source which reads a stored member from the zip file directly.
+// A compressed member is not io.ReaderAt and is spooled to a temporary file which is removed by the returned closer.
+func zipMemberSource(zipPath string, zf io.ReaderAt, f *zip.File) (*Source, io.Closer, error) {
+	name := zipMemberName(zipPath, f.Name)
+	if f.Method == zip.Store {
+		off, err := f.DataOffset()
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't read %s: %w", name, err)
+		}
+		size := int64(f.UncompressedSize64)
+		src := NewSource(name, io.NewSectionReader(zf, off, size), size)
+		src.Mode = f.Mode().Perm()
+		return src, nil, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	tmp, size, err := spool(rc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read %s: %w", name, err)
+	}
+	src := NewSource(name, tmp, size)
+	src.Mode = f.Mode().Perm()
+	return src, tmp, nil
+}
+
+// isObjectMember returns true if the member is a Mach-O file, a fat file or an archive
+func isObjectMember(f *zip.File) (bool, error) {
+	if !f.Mode().IsRegular() {
+		return false, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	buf := make([]byte, 40)
+	n, err := io.ReadFull(rc, buf)
+	if n == 0 {
+		return false, nil
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	_, err = inspectFile(bytes.NewReader(buf[:n]), f.Name)
+	return err == nil, nil
+}
+
+// expandZips replaces zip files of the inputs with their members which are Mach-O files, fat files or archives
+func (l *Lipo) expandZips(inputs []string) ([]string, error) {
+	expanded := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		if l.hasSource(in) || !isZip(in) {
+			expanded = append(expanded, in)
+			continue
+		}
+
+		zf, zr, err := openZip(in)
+		if err != nil {
+			return nil, err
+		}
+		// stored members are read from the zip file until Close
+		l.own(zf)
+
+		n := len(expanded)
+		for _, f := range zr.File {
+			ok, err := isObjectMember(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", zipMemberName(in, f.Name), err)
+			}
+			if !ok {
+				continue
+			}
+			src, c, err := zipMemberSource(in, zf, f)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				l.own(c)
+			}
+			l.addSources(src)
+			expanded = append(expanded, src.Name)
+		}
+
+		if n == len(expanded) {
+			return nil, fmt.Errorf("zip file %s does not contain any Mach-O files", in)
+		}
+	}
+	return expanded, nil
+}
+
+// KeepArchesZip writes the zip file to the output with only the architectures in every fat file of the members.
+// Other members are copied as they are. The results contain only the changed members.
+func (l *Lipo) KeepArchesZip(zipPath string, arches ...string) ([]*KeepResult, error) {
+	return l.KeepArchesZipContext(context.Background(), zipPath, arches...)
+}
+
+// KeepArchesZipContext is KeepArchesZip which stops writing the output when the context is done
+func (l *Lipo) KeepArchesZipContext(ctx context.Context, zipPath string, arches ...string) ([]*KeepResult, error) {
+	if err := validateKeepArches(arches); err != nil {
+		return nil, err
+	}
+
+	perm, err := l.perm(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	zf, zr, err := openZip(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zf.Close()
+
+	results := []*KeepResult{}
+	write := func(out io.Writer) error {
+		zw := zip.NewWriter(out)
+		if err := zw.SetComment(zr.Comment); err != nil {
+			return err
+		}
+
+		for _, f := range zr.File {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			r, err := l.keepZipMember(ctx, zipPath, zf, f, arches, zw)
+			if err != nil {
+				return err
+			}
+			if r == nil {
+				if err := zw.Copy(f); err != nil {
+					return err
+				}
+				continue
+			}
+			results = append(results, r)
+		}
+		return zw.Close()
+	}
+
+	if err := l.writeOutput(perm, write, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// rewrittenHeader returns the header of the member whose contents are changed.
+// The creator version is kept since unzip ignores the unix mode of the external attributes without it.
+func rewrittenHeader(f *zip.File) *zip.FileHeader {
+	hdr := f.FileHeader
+	hdr.CRC32 = 0
+	hdr.CompressedSize, hdr.UncompressedSize = 0, 0
+	hdr.CompressedSize64, hdr.UncompressedSize64 = 0, 0
+	hdr.Extra = stripZipExtra(hdr.Extra)
+	return &hdr
+}
+
+// stripZipExtra removes the zip64 and the extended timestamp fields which zip.Writer writes again
+func stripZipExtra(extra []byte) []byte {
+	const (
+		zip64ExtraID    = 0x0001
+		extTimeExtraID  = 0x5455
+		extraHeaderSize = 4
+	)
+	ret := []byte{}
+	for len(extra) >= extraHeaderSize {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:])) + extraHeaderSize
+		if size > len(extra) {
+			break
+		}
+		if id != zip64ExtraID && id != extTimeExtraID {
+			ret = append(ret, extra[:size]...)
+		}
+		extra = extra[size:]
+	}
+	return ret
+}
+
+// keepZipMember writes the thinned member to `zw` and returns the result.
+// It returns nil without writing if the member is not changed.
+func (l *Lipo) keepZipMember(ctx context.Context, zipPath string, zf io.ReaderAt, f *zip.File, arches []string, zw *zip.Writer) (*KeepResult, error) {
+	ok, err := isObjectMember(f)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	src, c, err := zipMemberSource(zipPath, zf, f)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		defer c.Close()
+	}
+
+	zl := New(WithSources(src))
+	zl.verifyOutput = l.verifyOutput
+	cw := &countWriter{}
+	removed, err := zl.keep(ctx, src.Name, arches, func() (*Lipo, error) {
+		w, err := zw.CreateHeader(rewrittenHeader(f))
+		if err != nil {
+			return nil, err
+		}
+		cw.w = w
+		return New(WithDestination(cw)), nil
+	})
+	if err != nil || removed == nil {
+		return nil, err
+	}
+
+	return &KeepResult{
+		Path:    src.Name,
+		Removed: removed,
+		Before:  src.Size,
+		After:   cw.n,
+	}, nil
+}
+
+// countWriter counts bytes written to the member
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,268 @@
+package lipo_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/lipo"
+	"github.com/konoui/lipo/pkg/testlipo"
+)
+
+const (
+	zipFatMember  = "Payload/App.app/App"
+	zipThinMember = "Payload/App.app/Frameworks/thin"
+	zipTextMember = "Payload/App.app/Info.plist"
+)
+
+func writeZip(t *testing.T, p string, members [][2]string) {
+	t.Helper()
+	writeZipWithMethod(t, p, members, zip.Deflate)
+}
+
+func writeZipWithMethod(t *testing.T, p string, members [][2]string, method uint16) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, m := range members {
+		data, err := os.ReadFile(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr := &zip.FileHeader{Name: m[0], Method: method}
+		hdr.SetMode(0755)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readZipMember(t *testing.T, zipPath, member, out string) {
+	t.Helper()
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	rc, err := zr.Open(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, out, data)
+}
+
+func setupZip(t *testing.T, p *testlipo.TestLipo, ext string) string {
+	t.Helper()
+	text := filepath.Join(p.Dir, "Info.plist")
+	writeFile(t, text, []byte("<plist></plist>"))
+
+	zipPath := filepath.Join(p.Dir, "App"+ext)
+	writeZip(t, zipPath, [][2]string{
+		{zipFatMember, p.FatBin},
+		{zipThinMember, p.Bin(t, "arm64")},
+		{zipTextMember, text},
+	})
+	return zipPath
+}
+
+func TestLipo_Zip(t *testing.T) {
+	t.Run("info", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		zipPath := setupZip(t, p, ".ipa")
+
+		l := lipo.New(lipo.WithInputs(zipPath))
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		l.Info(stdout, stderr)
+		if stderr.Len() != 0 {
+			t.Fatal(stderr.String())
+		}
+
+		want := p.Info(t, p.FatBin, p.Bin(t, "arm64"))
+		want = strings.ReplaceAll(want, p.FatBin, zipPath+"("+zipFatMember+")")
+		want = strings.ReplaceAll(want, p.Bin(t, "arm64"), zipPath+"("+zipThinMember+")")
+		if got := stdout.String(); want != got {
+			t.Errorf("\nwant:\n%s\ngot:\n%s", want, got)
+		}
+	})
+
+	t.Run("archs and verify_arch", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		zipPath := setupZip(t, p, ".ipa")
+
+		want, err := lipo.New(lipo.WithInputs(p.FatBin)).Archs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := lipo.New(lipo.WithInputs(zipPath))
+		defer l.Close()
+		got, err := l.Archs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v got %v", want, got)
+		}
+
+		// every member must contain the architectures
+		for arch, want := range map[string]bool{"arm64": true, "x86_64": false} {
+			l := lipo.New(lipo.WithInputs(zipPath))
+			defer l.Close()
+			got, err := l.VerifyArch(arch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want != got {
+				t.Errorf("%s: want %v got %v", arch, want, got)
+			}
+		}
+	})
+
+	t.Run("member", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		zipPath := setupZip(t, p, ".zip")
+
+		got := filepath.Join(p.Dir, gotName(t))
+		l := lipo.New(lipo.WithInputs(zipPath+"("+zipFatMember+")"), lipo.WithOutput(got))
+		if err := l.Thin("x86_64"); err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join(p.Dir, wantName(t))
+		p.Thin(t, want, p.FatBin, "x86_64")
+		diffSha256(t, want, got)
+	})
+
+	t.Run("stored and deflated members", func(t *testing.T) {
+		// deflated members are spooled to temporary files which are removed on Close
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		stored := filepath.Join(p.Dir, "stored.zip")
+		writeZipWithMethod(t, stored, [][2]string{{zipFatMember, p.FatBin}}, zip.Store)
+		deflated := setupZip(t, p, ".zip")
+
+		l := lipo.New(lipo.WithInputs(stored, deflated))
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		l.Info(stdout, stderr)
+		if stderr.Len() != 0 {
+			t.Fatal(stderr.String())
+		}
+		if got := strings.Count(stdout.String(), "\n"); got != 3 {
+			t.Errorf("want 3 lines got:\n%s", stdout.String())
+		}
+
+		if entries, _ := os.ReadDir(tmp); len(entries) == 0 {
+			t.Error("want spooled members")
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+			t.Errorf("temporary files are not removed: %v", entries)
+		}
+	})
+
+	t.Run("member-not-found", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		zipPath := setupZip(t, p, ".zip")
+
+		l := lipo.New(lipo.WithInputs(zipPath + "(not-found)"))
+		if _, err := l.Archs(); err == nil {
+			t.Error("want an error")
+		}
+	})
+
+	t.Run("no-mach-o", func(t *testing.T) {
+		p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+		text := filepath.Join(p.Dir, "Info.plist")
+		writeFile(t, text, []byte("<plist></plist>"))
+		zipPath := filepath.Join(p.Dir, "empty.zip")
+		writeZip(t, zipPath, [][2]string{{zipTextMember, text}})
+
+		l := lipo.New(lipo.WithInputs(zipPath))
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		l.Info(stdout, stderr)
+		if !strings.Contains(stderr.String(), "does not contain any Mach-O files") {
+			t.Errorf("unexpected error: %s", stderr.String())
+		}
+	})
+}
+
+func TestLipo_KeepArchesZip(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+	zipPath := setupZip(t, p, ".ipa")
+
+	out := filepath.Join(p.Dir, "App-arm64.ipa")
+	l := lipo.New(lipo.WithOutput(out))
+	results, err := l.KeepArchesZip(zipPath, "arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("want 1 changed member got %d", len(results))
+	}
+	r := results[0]
+	if r.Path != zipPath+"("+zipFatMember+")" || len(r.Removed) != 1 || r.Removed[0] != "x86_64" || r.Saved() <= 0 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == zipFatMember && f.Mode() != 0755 {
+			t.Errorf("want mode %v got %v", os.FileMode(0755), f.Mode())
+		}
+		if f.Name == zipFatMember && int64(f.UncompressedSize64) != r.After {
+			t.Errorf("want size %d got %d", f.UncompressedSize64, r.After)
+		}
+	}
+
+	got := filepath.Join(p.Dir, gotName(t))
+	readZipMember(t, out, zipFatMember, got)
+	want := filepath.Join(p.Dir, wantName(t))
+	p.Thin(t, want, p.FatBin, "arm64")
+	testlipo.DiffSha256(t, want, got)
+
+	// other members are not changed
+	gotThin := filepath.Join(p.Dir, gotName(t)+"-thin")
+	readZipMember(t, out, zipThinMember, gotThin)
+	testlipo.DiffSha256(t, p.Bin(t, "arm64"), gotThin)
+
+	gotText := filepath.Join(p.Dir, gotName(t)+"-text")
+	readZipMember(t, out, zipTextMember, gotText)
+	testlipo.DiffSha256(t, filepath.Join(p.Dir, "Info.plist"), gotText)
+
+	// the zip file is not changed in place
+	if _, err := lipo.New().KeepArches(zipPath, "arm64"); err == nil {
+		t.Error("want an error")
+	}
+}
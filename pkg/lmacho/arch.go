@@ -3,8 +3,10 @@ package lmacho
 import (
 	"debug/macho"
 	"fmt"
+	"slices"
 
 	"github.com/konoui/go-qsort"
+	"github.com/konoui/lipo/pkg/util"
 )
 
 const (
@@ -70,8 +72,19 @@ func CmpArchFunc[T Object](i, j T) int {
 }
 
 // sortArches sorts and update offset by `arches`
-func sortAndUpdateArches(arches []*FatArch, magic uint32) error {
-	qsort.Slice(arches, CmpArchFunc)
+// If `order` is specified, arches are arranged in the order instead of CmpArchFunc.
+// hidden arches are placed after visible arches since `nfat_arch` does not count them.
+func sortAndUpdateArches(arches []*FatArch, magic uint32, order []string) error {
+	if len(order) > 0 {
+		if err := sortByOrder(arches, order); err != nil {
+			return err
+		}
+	} else {
+		qsort.Slice(arches, CmpArchFunc)
+	}
+	slices.SortStableFunc(arches, func(i, j *FatArch) int {
+		return cmpHidden(i.Hidden, j.Hidden)
+	})
 
 	// update offset
 	offset := FatHeaderSize() + FatArchHeaderSize(magic)*uint64(len(arches))
@@ -87,6 +100,48 @@ func sortAndUpdateArches(arches []*FatArch, magic uint32) error {
 	return nil
 }
 
+func sortByOrder(arches []*FatArch, order []string) error {
+	if dup := util.Duplicates(order, func(v string) string { return v }); dup != nil {
+		return fmt.Errorf("order %s specified multiple times", *dup)
+	}
+
+	idx := make(map[string]int, len(order))
+	for i, o := range order {
+		idx[o] = i
+	}
+
+	for _, a := range arches {
+		if _, ok := idx[a.CPUString()]; !ok {
+			return fmt.Errorf("order does not contain architecture %s (all architectures must be specified)", a.CPUString())
+		}
+	}
+
+	if len(order) != len(arches) {
+		cpus := util.ExistenceMap(arches, func(a *FatArch) string { return a.CPUString() })
+		for _, o := range order {
+			if _, ok := cpus[o]; !ok {
+				return fmt.Errorf("order %s specified but resulting fat file does not contain that architecture", o)
+			}
+		}
+	}
+
+	slices.SortStableFunc(arches, func(i, j *FatArch) int {
+		return idx[i.CPUString()] - idx[j.CPUString()]
+	})
+	return nil
+}
+
+func cmpHidden(i, j bool) int {
+	switch {
+	case i == j:
+		return 0
+	case i:
+		return 1
+	default:
+		return -1
+	}
+}
+
 func hasDuplicatesErr[T Object](arches []T) error {
 	seenArches := make(map[uint64]bool, len(arches))
 	for _, a := range arches {
@@ -0,0 +1,43 @@
+//go:build linux && (amd64 || arm64)
+
+package lmacho
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// see linux/fs.h
+const ficlonerange = 0x4020940d
+
+type fileCloneRange struct {
+	srcFd      int64
+	srcOffset  uint64
+	srcLength  uint64
+	destOffset uint64
+}
+
+// cloneFileRange shares `n` bytes at `off` of `src` with the current offset of `dst` by FICLONERANGE.
+// It fails unless the file system supports reflinks and the ranges are aligned to its block size.
+func cloneFileRange(dst, src *os.File, off, n int64) error {
+	dstOff, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	arg := &fileCloneRange{
+		srcFd:      int64(src.Fd()),
+		srcOffset:  uint64(off),
+		srcLength:  uint64(n),
+		destOffset: uint64(dstOff),
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlonerange, uintptr(unsafe.Pointer(arg)))
+	if errno != 0 {
+		return errno
+	}
+
+	_, err = dst.Seek(n, io.SeekCurrent)
+	return err
+}
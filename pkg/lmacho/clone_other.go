@@ -0,0 +1,12 @@
+//go:build !(linux && (amd64 || arm64))
+
+package lmacho
+
+import (
+	"errors"
+	"os"
+)
+
+func cloneFileRange(dst, src *os.File, off, n int64) error {
+	return errors.ErrUnsupported
+}
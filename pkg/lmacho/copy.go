@@ -0,0 +1,99 @@
+package lmacho
+
+import (
+	"io"
+	"os"
+)
+
+// copyChunkSize is the size of a chunk copied between checks of the context
+const copyChunkSize = 8 << 20
+
+// outer is implemented by readers which read a range of an underlying reader like io.SectionReader
+type outer interface {
+	Outer() (r io.ReaderAt, off int64, n int64)
+}
+
+// fileRange returns the file and the offset which `r` reads from
+func fileRange(r io.ReaderAt) (*os.File, int64, bool) {
+	off := int64(0)
+	for {
+		switch v := r.(type) {
+		case *os.File:
+			return v, off, true
+		case outer:
+			var o int64
+			r, o, _ = v.Outer()
+			off += o
+		default:
+			return nil, 0, false
+		}
+	}
+}
+
+// copyFile copies `n` bytes at `off` of `src` to the current offset of `dst`.
+// The range is cloned if the file system supports it. Otherwise it is copied by copy_file_range(2)
+// and falls back to a userspace copy if the kernel can't copy it e.g. across file systems.
+// The range is read by the offset so that the offset of `src` shared with other readers is not moved.
+func (pw *progressWriter) copyFile(dst, src *os.File, off, n int64) error {
+	if err := pw.ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := cloneFileRange(dst, src, off, n); err == nil {
+		pw.advance(n)
+		return nil
+	}
+
+	for n > 0 {
+		if err := pw.ctx.Err(); err != nil {
+			return err
+		}
+
+		written, err := copyFileRange(dst, src, off, min(n, copyChunkSize))
+		pw.advance(written)
+		off, n = off+written, n-written
+		if err != nil {
+			if !copyRangeUnsupported(err) {
+				return err
+			}
+			break
+		}
+	}
+
+	sr := io.NewSectionReader(src, off, n)
+	for n > 0 {
+		if err := pw.ctx.Err(); err != nil {
+			return err
+		}
+
+		written, err := io.CopyN(dst, sr, min(n, copyChunkSize))
+		pw.advance(written)
+		if err != nil {
+			return err
+		}
+		n -= written
+	}
+	return nil
+}
+
+// skipHole seeks `n` bytes instead of writing zeros if the current offset is the end of the file
+func skipHole(f *os.File, n int64) (bool, error) {
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		// not seekable e.g. pipe
+		return false, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() > off {
+		return false, nil
+	}
+
+	if _, err := f.Seek(n, io.SeekCurrent); err != nil {
+		return false, err
+	}
+	return true, nil
+}
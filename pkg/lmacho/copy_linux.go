@@ -0,0 +1,46 @@
+//go:build linux
+
+package lmacho
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyFileRange copies `n` bytes at `off` of `src` to the current offset of `dst` by copy_file_range(2) in the kernel.
+// The offsets are passed explicitly so that the offset of `src` shared with other readers is not moved.
+func copyFileRange(dst, src *os.File, off, n int64) (int64, error) {
+	dstOff, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	written := int64(0)
+	for written < n {
+		c, cerr := unix.CopyFileRange(int(src.Fd()), &off, int(dst.Fd()), &dstOff, int(n-written), 0)
+		if cerr == nil && c == 0 {
+			cerr = io.ErrUnexpectedEOF
+		}
+		written += int64(c)
+		if cerr != nil {
+			err = cerr
+			break
+		}
+	}
+
+	if _, serr := dst.Seek(written, io.SeekCurrent); serr != nil && err == nil {
+		err = serr
+	}
+	return written, err
+}
+
+// copyRangeUnsupported reports whether the range can't be copied in the kernel but can be copied in userspace
+func copyRangeUnsupported(err error) bool {
+	return errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.ENOSYS) ||
+		errors.Is(err, unix.EOPNOTSUPP)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package lmacho
+
+import (
+	"errors"
+	"os"
+)
+
+func copyFileRange(dst, src *os.File, off, n int64) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func copyRangeUnsupported(err error) bool {
+	return errors.Is(err, errors.ErrUnsupported)
+}
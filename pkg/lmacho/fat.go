@@ -40,6 +40,7 @@ type Object interface {
 var (
 	_ Object = &FatArch{}
 	_ Object = &Arch{}
+	_ Object = &BlankArch{}
 )
 
 // FatArch presents an object of fat file
@@ -90,6 +91,11 @@ func (fa *FatArch) Seek(offset int64, whence int) (int64, error) {
 	return fa.sr.Seek(offset, whence)
 }
 
+// Outer returns the underlying reader, the offset and the size like io.SectionReader
+func (fa *FatArch) Outer() (io.ReaderAt, int64, int64) {
+	return fa.sr.Outer()
+}
+
 // Arch presents an object of thin file
 type Arch struct {
 	cpu    Cpu
@@ -135,6 +141,59 @@ func (a *Arch) Seek(offset int64, whence int) (int64, error) {
 	return a.sr.Seek(offset, whence)
 }
 
+// Outer returns the underlying reader, the offset and the size like io.SectionReader
+func (a *Arch) Outer() (io.ReaderAt, int64, int64) {
+	return a.sr.Outer()
+}
+
+// BlankArch presents a placeholder object which has no data.
+// see -arch_blank of cctools lipo
+type BlankArch struct {
+	cpu    Cpu
+	subCpu SubCpu
+}
+
+func NewBlankArch(cpu Cpu, subCpu SubCpu) *BlankArch {
+	return &BlankArch{cpu: cpu, subCpu: subCpu}
+}
+
+func (b *BlankArch) CPU() Cpu {
+	return b.cpu
+}
+
+func (b *BlankArch) SubCPU() SubCpu {
+	return b.subCpu
+}
+
+func (b *BlankArch) Size() uint64 {
+	return 0
+}
+
+func (b *BlankArch) Align() uint32 {
+	return 0
+}
+
+func (b *BlankArch) Type() macho.Type {
+	return 0
+}
+
+func (b *BlankArch) CPUString() string {
+	return ToCpuString(b.CPU(), b.SubCPU())
+}
+
+func (b *BlankArch) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (b *BlankArch) ReadAt(p []byte, off int64) (int, error) {
+	return 0, io.EOF
+}
+
+// IsBlank returns true if the object is a placeholder which has no data
+func IsBlank(obj Object) bool {
+	return obj.Size() == 0
+}
+
 type FormatError struct {
 	Err error
 }
@@ -146,6 +205,8 @@ func (e *FormatError) Error() string {
 type FatFile struct {
 	FatHeader
 	Arches []*FatArch
+	// Warnings holds messages about unusual hidden entries
+	Warnings []string
 }
 
 // NewFatFile is wrapper for Fat NewFatIter
@@ -170,6 +231,7 @@ func NewFatFile(ra io.ReaderAt) (*FatFile, error) {
 
 		fa.Arches = append(fa.Arches, a)
 	}
+	fa.Warnings = r.Warnings
 	return fa, nil
 }
 
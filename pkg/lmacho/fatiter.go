@@ -12,6 +12,8 @@ import (
 type FatIter struct {
 	r         *io.SectionReader
 	FatHeader FatHeader
+	// Warnings holds messages about unusual hidden entries found by Next()
+	Warnings []string
 }
 
 func NewFatIter(r io.ReaderAt) (*FatIter, error) {
@@ -55,7 +57,8 @@ func (r *FatIter) Next() iter.Seq2[*FatArch, error] {
 				return
 			}
 
-			if nextNArch == 1 && err == nil {
+			// hidden entries are placed between the fat arch headers and the first object
+			if err == nil && !fa.Hidden && (firstObjectOffset == 0 || fa.Offset() < firstObjectOffset) {
 				firstObjectOffset = fa.Offset()
 			}
 			nextNArch++
@@ -87,20 +90,32 @@ func (r *FatIter) next(nextNArch uint32, firstObjectOffset uint64) (*FatArch, er
 	}
 
 	// for hidden
-	if nextFatArchHdrOffset+FatArchHeaderSize(magic) > firstObjectOffset {
+	hdrEnd := nextFatArchHdrOffset + FatArchHeaderSize(magic)
+	if hdrEnd > firstObjectOffset {
 		return nil, io.EOF
 	}
 
 	fa, err := load(magic, r.r, hr, true)
 	if err != nil {
-		return nil, fmt.Errorf("hideARM64: %w", err)
+		return nil, fmt.Errorf("hidden arch: %w", err)
 	}
 
-	if fa.CPU() != TypeArm64 {
-		// TODO handle error
+	// the rest of the gap is zero-filled
+	if fa.CPU() == 0 && fa.SubCPU() == 0 && fa.Offset() == 0 && fa.Size() == 0 {
+		return nil, io.EOF
+	}
+
+	if fa.Offset() < hdrEnd {
+		r.Warnings = append(r.Warnings,
+			fmt.Sprintf("ignoring hidden entry (cputype (%d) cpusubtype (%d)) at offset %d overlapping fat headers", fa.CPU(), fa.SubCPU() & ^MaskSubCpuType, nextFatArchHdrOffset))
 		return nil, io.EOF
 	}
 
+	if fa.CPU() != TypeArm64 {
+		r.Warnings = append(r.Warnings,
+			fmt.Sprintf("hidden architecture %s is not arm64", fa.CPUString()))
+	}
+
 	return fa, nil
 }
 
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package lmacho
+
+import "os"
+
+// appendOnly always reports true since the flags of `f` are unknown
+func appendOnly(f *os.File) bool {
+	return true
+}
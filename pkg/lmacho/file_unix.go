@@ -0,0 +1,18 @@
+//go:build unix
+
+package lmacho
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// appendOnly reports whether writes to `f` always go to the end of the file regardless of the offset
+func appendOnly(f *os.File) bool {
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFL, 0)
+	if err != nil {
+		return true
+	}
+	return flags&unix.O_APPEND != 0
+}
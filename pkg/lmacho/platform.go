@@ -0,0 +1,154 @@
+package lmacho
+
+import (
+	"debug/macho"
+	"fmt"
+)
+
+// Platform is a platform of LC_BUILD_VERSION
+// https://github.com/apple-oss-distributions/cctools/blob/cctools-1021.4/include/mach-o/loader.h#L1272-L1288
+type Platform uint32
+
+const (
+	PlatformUnknown           Platform = 0
+	PlatformMacOS             Platform = 1
+	PlatformIOS               Platform = 2
+	PlatformTVOS              Platform = 3
+	PlatformWatchOS           Platform = 4
+	PlatformBridgeOS          Platform = 5
+	PlatformMacCatalyst       Platform = 6
+	PlatformIOSSimulator      Platform = 7
+	PlatformTVOSSimulator     Platform = 8
+	PlatformWatchOSSimulator  Platform = 9
+	PlatformDriverKit         Platform = 10
+	PlatformVisionOS          Platform = 11
+	PlatformVisionOSSimulator Platform = 12
+)
+
+const (
+	loadCmdVersionMinMacOSX   macho.LoadCmd = 0x24
+	loadCmdVersionMinIPhoneOS macho.LoadCmd = 0x25
+	loadCmdVersionMinTVOS     macho.LoadCmd = 0x2f
+	loadCmdVersionMinWatchOS  macho.LoadCmd = 0x30
+	loadCmdBuildVersion       macho.LoadCmd = 0x32
+)
+
+var platformNames = map[Platform]string{
+	PlatformMacOS:             "macos",
+	PlatformIOS:               "ios",
+	PlatformTVOS:              "tvos",
+	PlatformWatchOS:           "watchos",
+	PlatformBridgeOS:          "bridgeos",
+	PlatformMacCatalyst:       "maccatalyst",
+	PlatformIOSSimulator:      "iossimulator",
+	PlatformTVOSSimulator:     "tvossimulator",
+	PlatformWatchOSSimulator:  "watchossimulator",
+	PlatformDriverKit:         "driverkit",
+	PlatformVisionOS:          "xros",
+	PlatformVisionOSSimulator: "xrossimulator",
+}
+
+func (p Platform) String() string {
+	if v, ok := platformNames[p]; ok {
+		return v
+	}
+	return fmt.Sprintf("unknown(%d)", uint32(p))
+}
+
+// OS returns the operating system of the platform e.g. `ios` for `iossimulator` and `maccatalyst`
+func (p Platform) OS() string {
+	switch p {
+	case PlatformMacCatalyst, PlatformIOSSimulator:
+		return PlatformIOS.String()
+	case PlatformTVOSSimulator:
+		return PlatformTVOS.String()
+	case PlatformWatchOSSimulator:
+		return PlatformWatchOS.String()
+	case PlatformVisionOSSimulator:
+		return PlatformVisionOS.String()
+	default:
+		return p.String()
+	}
+}
+
+// Variant returns `simulator`, `maccatalyst` or an empty string for devices
+func (p Platform) Variant() string {
+	switch p {
+	case PlatformIOSSimulator, PlatformTVOSSimulator, PlatformWatchOSSimulator, PlatformVisionOSSimulator:
+		return "simulator"
+	case PlatformMacCatalyst:
+		return "maccatalyst"
+	default:
+		return ""
+	}
+}
+
+var tripleOSNames = map[Platform]string{
+	PlatformMacOS:             "macosx",
+	PlatformIOS:               "ios",
+	PlatformTVOS:              "tvos",
+	PlatformWatchOS:           "watchos",
+	PlatformBridgeOS:          "bridgeos",
+	PlatformMacCatalyst:       "ios-macabi",
+	PlatformIOSSimulator:      "ios-simulator",
+	PlatformTVOSSimulator:     "tvos-simulator",
+	PlatformWatchOSSimulator:  "watchos-simulator",
+	PlatformDriverKit:         "driverkit",
+	PlatformVisionOS:          "xros",
+	PlatformVisionOSSimulator: "xros-simulator",
+}
+
+// Triple returns the target triple of the cpu and the platform e.g. `arm64-apple-macosx`
+func Triple(cpu string, p Platform) (string, bool) {
+	v, ok := tripleOSNames[p]
+	if !ok {
+		return "", false
+	}
+	return cpu + "-apple-" + v, true
+}
+
+// ToPlatform returns the platform of the operating system and the variant returned by OS and Variant
+func ToPlatform(os, variant string) (Platform, bool) {
+	for p := range platformNames {
+		if p.OS() == os && p.Variant() == variant {
+			return p, true
+		}
+	}
+	return PlatformUnknown, false
+}
+
+// BuildPlatform returns the platform from LC_BUILD_VERSION or LC_VERSION_MIN_* of the Mach-O file.
+// PlatformUnknown is returned if the file has none of them.
+func BuildPlatform(f *macho.File) Platform {
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		switch macho.LoadCmd(f.ByteOrder.Uint32(raw)) {
+		case loadCmdBuildVersion:
+			if len(raw) >= 12 {
+				return Platform(f.ByteOrder.Uint32(raw[8:]))
+			}
+		case loadCmdVersionMinMacOSX:
+			return PlatformMacOS
+		case loadCmdVersionMinIPhoneOS:
+			// simulators before LC_BUILD_VERSION are identified by the cpu
+			if f.Cpu == macho.CpuAmd64 || f.Cpu == macho.Cpu386 {
+				return PlatformIOSSimulator
+			}
+			return PlatformIOS
+		case loadCmdVersionMinTVOS:
+			if f.Cpu == macho.CpuAmd64 {
+				return PlatformTVOSSimulator
+			}
+			return PlatformTVOS
+		case loadCmdVersionMinWatchOS:
+			if f.Cpu == macho.CpuAmd64 || f.Cpu == macho.Cpu386 {
+				return PlatformWatchOSSimulator
+			}
+			return PlatformWatchOS
+		}
+	}
+	return PlatformUnknown
+}
@@ -0,0 +1,148 @@
+package lmacho
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Progress presents bytes written of an architecture and of the whole output
+type Progress struct {
+	// Arch is the cpu string of the architecture being written
+	Arch string
+	// Written is bytes written of the architecture
+	Written uint64
+	// Size is the size of the architecture
+	Size uint64
+	// TotalWritten is bytes written of the output including headers and alignment
+	TotalWritten uint64
+	// Total is the size of the output
+	Total uint64
+}
+
+// ProgressFunc is called every time a chunk of an architecture is written
+type ProgressFunc func(p Progress)
+
+// CopyObjectContext copies the object to `w` as a thin file with the progress.
+// It stops copying when the context is done.
+func CopyObjectContext(ctx context.Context, w io.Writer, obj Object, fn ProgressFunc) error {
+	pw := newProgressWriter(ctx, w, obj.Size(), fn)
+	if err := pw.copyObject(obj); err != nil {
+		return fmt.Errorf("error write binary data: %w", err)
+	}
+	return nil
+}
+
+// progressWriter checks the context before each write and reports bytes written of an object
+type progressWriter struct {
+	ctx context.Context
+	w   io.Writer
+	// f is the destination if it is written by offsets
+	f       *os.File
+	fn      ProgressFunc
+	p       Progress
+	copying bool
+	// skipped is true if padding is skipped as a hole
+	skipped bool
+}
+
+func newProgressWriter(ctx context.Context, w io.Writer, total uint64, fn ProgressFunc) *progressWriter {
+	return &progressWriter{ctx: ctx, w: w, f: seekableFile(w), fn: fn, p: Progress{Total: total}}
+}
+
+// seekableFile returns the destination if it is a regular file which is not opened with O_APPEND.
+// Ranges are cloned or copied in the kernel and padding is skipped as a hole only for the file
+// since seeking is ignored by O_APPEND and is not supported by pipes.
+func seekableFile(w io.Writer) *os.File {
+	f, ok := w.(*os.File)
+	if !ok {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() || appendOnly(f) {
+		return nil
+	}
+	return f
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	if err := pw.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := pw.w.Write(b)
+	pw.advance(int64(n))
+	return n, err
+}
+
+func (pw *progressWriter) advance(n int64) {
+	pw.p.TotalWritten += uint64(n)
+	if pw.copying {
+		pw.p.Written += uint64(n)
+		if pw.fn != nil {
+			pw.fn(pw.p)
+		}
+	}
+}
+
+func (pw *progressWriter) copyObject(obj Object) error {
+	pw.p.Arch, pw.p.Written, pw.p.Size = obj.CPUString(), 0, obj.Size()
+	pw.copying = true
+	defer func() { pw.copying = false }()
+
+	size := int64(obj.Size())
+	if pw.f != nil && size > 0 {
+		if src, off, ok := fileRange(obj); ok {
+			return pw.copyFile(pw.f, src, off, size)
+		}
+	}
+
+	_, err := io.CopyN(pw, obj, size)
+	return err
+}
+
+// pad writes `n` zero bytes.
+// If the destination is a seekable file and the current offset is its end, the padding is skipped as a hole.
+func (pw *progressWriter) pad(n uint64) error {
+	if err := pw.ctx.Err(); err != nil {
+		return err
+	}
+
+	if pw.f != nil {
+		skipped, err := skipHole(pw.f, int64(n))
+		if err != nil {
+			return err
+		}
+		if skipped {
+			pw.skipped = true
+			pw.advance(int64(n))
+			return nil
+		}
+	}
+
+	_, err := pw.Write(make([]byte, n))
+	return err
+}
+
+// finish extends the destination file to the current offset if the last padding is skipped as a hole.
+// The last byte is written instead of truncating the file which may be supplied by the caller.
+func (pw *progressWriter) finish() error {
+	if pw.f == nil || !pw.skipped {
+		return nil
+	}
+
+	off, err := pw.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	info, err := pw.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < off {
+		_, err := pw.f.WriteAt([]byte{0}, off-1)
+		return err
+	}
+	return nil
+}
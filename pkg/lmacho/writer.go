@@ -1,20 +1,85 @@
 package lmacho
 
 import (
+	"context"
 	"debug/macho"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+
+	"github.com/konoui/lipo/pkg/util"
 )
 
-func CreateFat[T Object](w io.Writer, objects []T, fat64 bool, hideARM64 bool) error {
+type FatOption func(c *fatConfig)
+
+type fatConfig struct {
+	hidden   []string
+	order    []string
+	progress ProgressFunc
+}
+
+// WithHidden hides the specified architectures from the fat header.
+// Hidden fat arch headers are placed after `nfat_arch` headers like hideARM64.
+func WithHidden(cpuStrings ...string) FatOption {
+	return func(c *fatConfig) {
+		c.hidden = append(c.hidden, cpuStrings...)
+	}
+}
+
+// WithOrder arranges the fat arch headers in the specified order instead of the cctools order.
+// All architectures must be specified exactly once.
+func WithOrder(cpuStrings ...string) FatOption {
+	return func(c *fatConfig) {
+		c.order = append(c.order, cpuStrings...)
+	}
+}
+
+// WithProgress reports bytes written of each architecture to `fn`
+func WithProgress(fn ProgressFunc) FatOption {
+	return func(c *fatConfig) {
+		c.progress = fn
+	}
+}
+
+func CreateFat[T Object](w io.Writer, objects []T, fat64 bool, hideARM64 bool, opts ...FatOption) error {
+	return CreateFatContext(context.Background(), w, objects, fat64, hideARM64, opts...)
+}
+
+// CreateFatContext is CreateFat which stops writing when the context is done
+func CreateFatContext[T Object](ctx context.Context, w io.Writer, objects []T, fat64 bool, hideARM64 bool, opts ...FatOption) error {
+	layout, err := NewFatLayout(objects, fat64, hideARM64, opts...)
+	if err != nil {
+		return err
+	}
+	return layout.WriteContext(ctx, w)
+}
+
+// FatLayout presents a planned fat file which is not written yet.
+// Arches are sorted and their offsets are filled. Objects[i] is the source of Arches[i].
+type FatLayout[T Object] struct {
+	FatHeader
+	Arches   []*FatArch
+	Objects  []T
+	progress ProgressFunc
+}
+
+// NewFatLayout validates objects and plans the fat header and offsets of them
+func NewFatLayout[T Object](objects []T, fat64 bool, hideARM64 bool, opts ...FatOption) (*FatLayout[T], error) {
 	if len(objects) == 0 {
-		return errors.New("file contains no images")
+		return nil, errors.New("file contains no images")
+	}
+
+	cfg := &fatConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
 	}
 
 	if err := validateHideARM64Objects(objects, hideARM64); err != nil {
-		return err
+		return nil, err
 	}
 
 	magic := MagicFat
@@ -23,19 +88,65 @@ func CreateFat[T Object](w io.Writer, objects []T, fat64 bool, hideARM64 bool) e
 	}
 
 	fatArches := newFatArches(objects)
-	hdr := makeFatHeader(fatArches, magic, hideARM64)
-	if err := sortAndUpdateArches(fatArches, hdr.Magic); err != nil {
-		return err
+	srcs := make(map[*FatArch]T, len(objects))
+	for i := range fatArches {
+		srcs[fatArches[i]] = objects[i]
+	}
+
+	if err := updateHidden(fatArches, hideARM64, cfg.hidden); err != nil {
+		return nil, err
+	}
+
+	hdr := makeFatHeader(fatArches, magic)
+	if err := sortAndUpdateArches(fatArches, hdr.Magic, cfg.order); err != nil {
+		return nil, err
+	}
+
+	if err := hasDuplicatesErr(fatArches); err != nil {
+		return nil, err
+	}
+
+	if err := checkMaxAlignBit(fatArches); err != nil {
+		return nil, err
 	}
 
-	if err := writeHeaders(w, hdr, fatArches); err != nil {
+	return &FatLayout[T]{
+		FatHeader: hdr,
+		Arches:    fatArches,
+		Objects:   util.Map(fatArches, func(fa *FatArch) T { return srcs[fa] }),
+		progress:  cfg.progress,
+	}, nil
+}
+
+// Size returns the size of the fat file
+func (l *FatLayout[T]) Size() uint64 {
+	size := FatHeaderSize() + FatArchHeaderSize(l.Magic)*uint64(len(l.Arches))
+	for _, fa := range l.Arches {
+		size = max(size, fa.Offset()+fa.Size())
+	}
+	return size
+}
+
+// Write writes the fat file to `w`
+func (l *FatLayout[T]) Write(w io.Writer) error {
+	return l.WriteContext(context.Background(), w)
+}
+
+// WriteContext writes the fat file to `w` and stops writing when the context is done
+func (l *FatLayout[T]) WriteContext(ctx context.Context, w io.Writer) error {
+	pw := newProgressWriter(ctx, w, l.Size(), l.progress)
+	if err := writeHeaders(pw, l.FatHeader, l.Arches); err != nil {
 		return err
 	}
 
-	if err := writeArches(w, fatArches, hdr.Magic); err != nil {
+	if err := writeArches(pw, l.Arches, l.Magic); err != nil {
 		return err
 	}
 
+	if err := pw.finish(); err != nil {
+		return fmt.Errorf("error alignment: %w", err)
+	}
+
 	return nil
 }
 
@@ -58,16 +169,8 @@ func newFatArches[T Object](objects []T) []*FatArch {
 	return arches
 }
 
-// writeHEaders validates inputs and write data to destination
+// writeHEaders writes headers to destination
 func writeHeaders(w io.Writer, hdr FatHeader, arches []*FatArch) error {
-	if err := hasDuplicatesErr(arches); err != nil {
-		return err
-	}
-
-	if err := checkMaxAlignBit(arches); err != nil {
-		return err
-	}
-
 	// write a fat header
 	// see https://cs.opensource.google/go/go/+/refs/tags/go1.18:src/debug/macho/fat.go;l=45
 	if err := binary.Write(w, binary.BigEndian, hdr); err != nil {
@@ -83,21 +186,20 @@ func writeHeaders(w io.Writer, hdr FatHeader, arches []*FatArch) error {
 	return nil
 }
 
-func writeArches(w io.Writer, arches []*FatArch, magic uint32) error {
+func writeArches(w *progressWriter, arches []*FatArch, magic uint32) error {
 	firstObjectOffset := FatHeaderSize() + FatArchHeaderSize(magic)*uint64(len(arches))
 	offset := firstObjectOffset
 	for _, fatArch := range arches {
 		if offset < fatArch.faHdr.Offset {
 			// write empty data for alignment
-			empty := make([]byte, fatArch.faHdr.Offset-offset)
-			if _, err := w.Write(empty); err != nil {
+			if err := w.pad(fatArch.faHdr.Offset - offset); err != nil {
 				return fmt.Errorf("error alignment: %w", err)
 			}
 			offset = fatArch.faHdr.Offset
 		}
 
 		// write binary data
-		if _, err := io.CopyN(w, fatArch, int64(fatArch.Size())); err != nil {
+		if err := w.copyObject(fatArch); err != nil {
 			return fmt.Errorf("error write binary data: %w", err)
 		}
 		offset += fatArch.Size()
@@ -128,25 +230,44 @@ func writeFatArchHeader(out io.Writer, hdr FatArchHeader, magic uint32) error {
 	return nil
 }
 
-func makeFatHeader[T Object](objects []T, magic uint32, hideARM64 bool) FatHeader {
-	var found bool
-	for _, fatArch := range objects {
-		if fatArch.CPU() == TypeArm {
-			found = true
-			break
+// updateHidden marks arches as hidden.
+// hideARM64 hides arm64 arches only if the arches contain an arm (32 bit) arch.
+func updateHidden(arches []*FatArch, hideARM64 bool, hidden []string) error {
+	if dup := util.Duplicates(hidden, func(v string) string { return v }); dup != nil {
+		return fmt.Errorf("hidden architecture %s specified multiple times", *dup)
+	}
+
+	m := util.ExistenceMap(hidden, func(v string) string { return v })
+	for _, a := range arches {
+		if _, ok := m[a.CPUString()]; ok {
+			a.Hidden = true
+			delete(m, a.CPUString())
+		}
+	}
+	for _, h := range hidden {
+		if _, ok := m[h]; ok {
+			return fmt.Errorf("hidden architecture %s specified but resulting fat file does not contain that architecture", h)
 		}
 	}
 
-	if !hideARM64 || !found {
-		return FatHeader{
-			Magic: magic,
-			NArch: uint32(len(objects)),
+	if hideARM64 && slices.ContainsFunc(arches, func(a *FatArch) bool { return a.CPU() == TypeArm }) {
+		for _, a := range arches {
+			if a.CPU() == TypeArm64 {
+				a.Hidden = true
+			}
 		}
 	}
 
+	if !slices.ContainsFunc(arches, func(a *FatArch) bool { return !a.Hidden }) {
+		return errors.New("all architectures are hidden, at least one visible architecture is required")
+	}
+	return nil
+}
+
+func makeFatHeader(arches []*FatArch, magic uint32) FatHeader {
 	narch := uint32(0)
-	for i := range objects {
-		if objects[i].CPU() == TypeArm64 {
+	for _, a := range arches {
+		if a.Hidden {
 			continue
 		}
 		narch++
@@ -1,10 +1,15 @@
 package lmacho_test
 
 import (
+	"bytes"
+	"context"
 	"debug/macho"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/konoui/lipo/pkg/lmacho"
@@ -73,3 +78,305 @@ func TestNewWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateFatWithHidden(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64", "arm64e"})
+	arches := openArches(t, p.Bins(t))
+
+	t.Run("hidden", func(t *testing.T) {
+		outPath := filepath.Join(t.TempDir(), "fat-hidden")
+		out, err := os.Create(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Close()
+
+		if err := lmacho.CreateFat(out, arches, false, false, lmacho.WithHidden("x86_64", "arm64e")); err != nil {
+			t.Fatal(err)
+		}
+
+		ff, err := lmacho.NewFatFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ff.NArch != 1 {
+			t.Errorf("nfat_arch want: 1, got: %d", ff.NArch)
+		}
+
+		got := []string{}
+		for _, a := range ff.Arches {
+			v := a.CPUString()
+			if a.Hidden {
+				v += " (hidden)"
+			}
+			got = append(got, v)
+		}
+		want := []string{"arm64", "x86_64 (hidden)", "arm64e (hidden)"}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want: %v, got: %v", want, got)
+		}
+
+		wantWarnings := []string{"hidden architecture x86_64 is not arm64"}
+		if !reflect.DeepEqual(wantWarnings, ff.Warnings) {
+			t.Errorf("want: %v, got: %v", wantWarnings, ff.Warnings)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tests := []struct {
+			hidden     []string
+			wantErrMsg string
+		}{
+			{
+				hidden:     []string{"armv7k"},
+				wantErrMsg: "hidden architecture armv7k specified but resulting fat file does not contain that architecture",
+			},
+			{
+				hidden:     []string{"x86_64", "x86_64"},
+				wantErrMsg: "hidden architecture x86_64 specified multiple times",
+			},
+			{
+				hidden:     []string{"x86_64", "arm64", "arm64e"},
+				wantErrMsg: "all architectures are hidden, at least one visible architecture is required",
+			},
+		}
+		for _, tt := range tests {
+			err := lmacho.CreateFat(io.Discard, arches, false, false, lmacho.WithHidden(tt.hidden...))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if err.Error() != tt.wantErrMsg {
+				t.Errorf("want: %s, got: %s", tt.wantErrMsg, err.Error())
+			}
+		}
+	})
+}
+
+func TestCreateFatWithOrder(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64", "arm64e"})
+	arches := openArches(t, p.Bins(t))
+
+	t.Run("order", func(t *testing.T) {
+		outPath := filepath.Join(t.TempDir(), "fat-order")
+		out, err := os.Create(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer out.Close()
+
+		want := []string{"arm64e", "arm64", "x86_64"}
+		if err := lmacho.CreateFat(out, arches, false, false, lmacho.WithOrder(want...)); err != nil {
+			t.Fatal(err)
+		}
+
+		ff, err := lmacho.NewFatFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		offset := uint64(0)
+		for _, a := range ff.Arches {
+			got = append(got, a.CPUString())
+			if a.Offset() < offset || a.Offset()%(1<<a.Align()) != 0 {
+				t.Errorf("%s: unexpected offset %d", a.CPUString(), a.Offset())
+			}
+			offset = a.Offset() + a.Size()
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want: %v, got: %v", want, got)
+		}
+
+		if _, err := macho.OpenFat(outPath); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tests := []struct {
+			order      []string
+			wantErrMsg string
+		}{
+			{
+				order:      []string{"arm64", "x86_64"},
+				wantErrMsg: "order does not contain architecture arm64e (all architectures must be specified)",
+			},
+			{
+				order:      []string{"arm64", "x86_64", "arm64e", "armv7k"},
+				wantErrMsg: "order armv7k specified but resulting fat file does not contain that architecture",
+			},
+			{
+				order:      []string{"arm64", "x86_64", "arm64", "arm64e"},
+				wantErrMsg: "order arm64 specified multiple times",
+			},
+		}
+		for _, tt := range tests {
+			err := lmacho.CreateFat(io.Discard, arches, false, false, lmacho.WithOrder(tt.order...))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if err.Error() != tt.wantErrMsg {
+				t.Errorf("want: %s, got: %s", tt.wantErrMsg, err.Error())
+			}
+		}
+	})
+}
+
+func openArches(t *testing.T, bins []string) []lmacho.Object {
+	t.Helper()
+
+	arches := []lmacho.Object{}
+	for _, bin := range bins {
+		f, err := os.Open(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := lmacho.NewArch(io.NewSectionReader(f, 0, info.Size()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		arches = append(arches, a)
+	}
+	return arches
+}
+
+func TestCreateFatContext(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+	arches := openArches(t, p.Bins(t))
+
+	t.Run("progress", func(t *testing.T) {
+		last := map[string]lmacho.Progress{}
+		var total lmacho.Progress
+		fn := func(v lmacho.Progress) {
+			last[v.Arch] = v
+			total = v
+		}
+		out := &bytes.Buffer{}
+		if err := lmacho.CreateFatContext(context.Background(), out, arches, false, false, lmacho.WithProgress(fn)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, a := range arches {
+			v, ok := last[a.CPUString()]
+			if !ok {
+				t.Fatalf("no progress of %s", a.CPUString())
+			}
+			if v.Written != a.Size() || v.Size != a.Size() {
+				t.Errorf("%s: want %d bytes got %d/%d", a.CPUString(), a.Size(), v.Written, v.Size)
+			}
+		}
+		if total.TotalWritten != total.Total || total.Total != uint64(out.Len()) {
+			t.Errorf("want total %d got %d/%d", out.Len(), total.TotalWritten, total.Total)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		fn := func(v lmacho.Progress) {
+			cancel()
+		}
+		err := lmacho.CreateFatContext(ctx, io.Discard, arches, false, false, lmacho.WithProgress(fn))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestCreateFatToFile(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64", "arm64e"})
+	arches := openArches(t, p.Bins(t))
+	cpu, subCpu, _ := lmacho.ToCpu("armv7k")
+	objects := append(arches, lmacho.NewBlankArch(cpu, subCpu))
+	// place the blank arch last to end the file with padding
+	order := lmacho.WithOrder("arm64", "x86_64", "arm64e", "armv7k")
+
+	for _, fat64 := range []bool{false, true} {
+		// seeking is ignored by O_APPEND e.g. `lipo -output - >> out`
+		for _, flag := range []int{os.O_TRUNC, os.O_APPEND} {
+			t.Run(fmt.Sprintf("fat64 %v append %v", fat64, flag == os.O_APPEND), func(t *testing.T) {
+				testCreateFatToFile(t, objects, fat64, flag, order)
+			})
+		}
+	}
+}
+
+func testCreateFatToFile(t *testing.T, objects []lmacho.Object, fat64 bool, flag int, order lmacho.FatOption) {
+	t.Helper()
+	want := &bytes.Buffer{}
+	if err := lmacho.CreateFat(want, objects, fat64, false, order); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.OpenFile(filepath.Join(t.TempDir(), "fat"), os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if err := lmacho.CreateFat(out, objects, fat64, false, order); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want.Bytes(), got) {
+		t.Errorf("want %d bytes got %d bytes, contents differ", want.Len(), len(got))
+	}
+}
+
+func TestCreateFatKeepsSourceOffsets(t *testing.T) {
+	p := testlipo.Setup(t, bm, []string{"arm64", "x86_64"})
+
+	files := []*os.File{}
+	arches := []lmacho.Object{}
+	for _, bin := range p.Bins(t) {
+		f, err := os.Open(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := lmacho.NewArch(io.NewSectionReader(f, 0, info.Size()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Seek(1, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+		arches = append(arches, a)
+	}
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "fat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if err := lmacho.CreateFat(out, arches, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the offsets of the sources shared with other readers are not moved
+	for _, f := range files {
+		off, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off != 1 {
+			t.Errorf("%s: the offset is moved to %d", f.Name(), off)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package plist reads and writes XML property lists of dictionaries, arrays, strings, integers and booleans.
+package plist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const header = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// Marshal returns the XML property list of v.
+// v consists of map[string]any, []any, []string, string, int, int64 and bool.
+// Keys of a dictionary are sorted.
+func Marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(header)
+	if err := marshal(buf, v, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</plist>\n")
+	return buf.Bytes(), nil
+}
+
+func marshal(buf *bytes.Buffer, v any, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	switch v := v.(type) {
+	case map[string]any:
+		buf.WriteString(indent + "<dict>\n")
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			buf.WriteString(indent + "\t<key>" + escape(k) + "</key>\n")
+			if err := marshal(buf, v[k], depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + "</dict>\n")
+	case []any:
+		buf.WriteString(indent + "<array>\n")
+		for _, e := range v {
+			if err := marshal(buf, e, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + "</array>\n")
+	case []string:
+		buf.WriteString(indent + "<array>\n")
+		for _, e := range v {
+			buf.WriteString(indent + "\t<string>" + escape(e) + "</string>\n")
+		}
+		buf.WriteString(indent + "</array>\n")
+	case string:
+		buf.WriteString(indent + "<string>" + escape(v) + "</string>\n")
+	case int:
+		buf.WriteString(indent + "<integer>" + strconv.Itoa(v) + "</integer>\n")
+	case int64:
+		buf.WriteString(indent + "<integer>" + strconv.FormatInt(v, 10) + "</integer>\n")
+	case bool:
+		buf.WriteString(indent + "<" + strconv.FormatBool(v) + "/>\n")
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+func escape(s string) string {
+	buf := &strings.Builder{}
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
+
+// Unmarshal parses the XML property list.
+// A dictionary is map[string]any, an array is []any, an integer is int64 and a real is float64.
+func Unmarshal(data []byte) (any, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("plist element not found")
+			}
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			if se.Name.Local != "plist" {
+				return nil, fmt.Errorf("unexpected element %s", se.Name.Local)
+			}
+			se, err := nextStart(d)
+			if err != nil {
+				return nil, err
+			}
+			return unmarshal(d, se)
+		}
+	}
+}
+
+// nextStart returns the next start element and fails at an end element
+func nextStart(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errEnd
+		}
+	}
+}
+
+var errEnd = errors.New("end element")
+
+func unmarshal(d *xml.Decoder, se xml.StartElement) (any, error) {
+	switch se.Name.Local {
+	case "dict":
+		m := map[string]any{}
+		for {
+			kse, err := nextStart(d)
+			if errors.Is(err, errEnd) {
+				return m, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if kse.Name.Local != "key" {
+				return nil, fmt.Errorf("want key in dict but got %s", kse.Name.Local)
+			}
+			var k string
+			if err := d.DecodeElement(&k, &kse); err != nil {
+				return nil, err
+			}
+			vse, err := nextStart(d)
+			if err != nil {
+				return nil, fmt.Errorf("value of key %s: %w", k, err)
+			}
+			v, err := unmarshal(d, vse)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+	case "array":
+		a := []any{}
+		for {
+			ese, err := nextStart(d)
+			if errors.Is(err, errEnd) {
+				return a, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := unmarshal(d, ese)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return se.Name.Local == "true", nil
+	}
+
+	var s string
+	if err := d.DecodeElement(&s, &se); err != nil {
+		return nil, err
+	}
+	switch se.Name.Local {
+	case "string", "date", "data":
+		return s, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	default:
+		return nil, fmt.Errorf("unsupported element %s", se.Name.Local)
+	}
+}
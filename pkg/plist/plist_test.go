@@ -0,0 +1,111 @@
+package plist_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konoui/lipo/pkg/plist"
+)
+
+func TestMarshal(t *testing.T) {
+	v := map[string]any{
+		"CFBundlePackageType": "XFWK",
+		"AvailableLibraries": []any{
+			map[string]any{
+				"LibraryIdentifier":      "ios-arm64_x86_64-simulator",
+				"SupportedArchitectures": []string{"arm64", "x86_64"},
+			},
+		},
+		"Version": 1,
+		"Enabled": true,
+	}
+
+	data, err := plist.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AvailableLibraries</key>
+	<array>
+		<dict>
+			<key>LibraryIdentifier</key>
+			<string>ios-arm64_x86_64-simulator</string>
+			<key>SupportedArchitectures</key>
+			<array>
+				<string>arm64</string>
+				<string>x86_64</string>
+			</array>
+		</dict>
+	</array>
+	<key>CFBundlePackageType</key>
+	<string>XFWK</string>
+	<key>Enabled</key>
+	<true/>
+	<key>Version</key>
+	<integer>1</integer>
+</dict>
+</plist>
+`
+	if got := string(data); got != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, got)
+	}
+
+	got, err := plist.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantV := map[string]any{
+		"CFBundlePackageType": "XFWK",
+		"AvailableLibraries": []any{
+			map[string]any{
+				"LibraryIdentifier":      "ios-arm64_x86_64-simulator",
+				"SupportedArchitectures": []any{"arm64", "x86_64"},
+			},
+		},
+		"Version": int64(1),
+		"Enabled": true,
+	}
+	if !reflect.DeepEqual(wantV, got) {
+		t.Errorf("want %#v got %#v", wantV, got)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    any
+		wantErr bool
+	}{
+		{
+			name: "escaped string and real",
+			data: `<plist version="1.0"><dict><key>a&amp;b</key><string>&lt;c&gt;</string><key>r</key><real>1.5</real><key>f</key><false/></dict></plist>`,
+			want: map[string]any{"a&b": "<c>", "r": 1.5, "f": false},
+		},
+		{
+			name:    "missing key",
+			data:    `<plist version="1.0"><dict><string>a</string></dict></plist>`,
+			wantErr: true,
+		},
+		{
+			name:    "not plist",
+			data:    `<dict></dict>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plist.Unmarshal([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("want %#v got %#v", tt.want, got)
+			}
+		})
+	}
+}
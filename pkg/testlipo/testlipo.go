@@ -329,6 +329,33 @@ func PatchFat64Reserved(t *testing.T, p string) {
 	fatalIf(t, err)
 }
 
+// PatchPlatform copies the thin 64-bit file and rewrites the platform of LC_BUILD_VERSION
+func PatchPlatform(t *testing.T, src, dst string, platform lmacho.Platform) {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	fatalIf(t, err)
+
+	mo, err := macho.Open(src)
+	fatalIf(t, err)
+	defer mo.Close()
+
+	// sizeof(mach_header_64)
+	off := uint32(32)
+	for range mo.Ncmd {
+		cmd := binary.LittleEndian.Uint32(data[off:])
+		size := binary.LittleEndian.Uint32(data[off+4:])
+		// LC_BUILD_VERSION
+		if cmd == 0x32 {
+			binary.LittleEndian.PutUint32(data[off+8:], uint32(platform))
+			fatalIf(t, os.WriteFile(dst, data, 0755))
+			return
+		}
+		off += size
+	}
+	t.Fatalf("LC_BUILD_VERSION is not found in %s", src)
+}
+
 func DiffPerm(t *testing.T, wantBin, gotBin string) {
 	wantInfo, err := os.Stat(wantBin)
 	fatalIf(t, err)